@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+)
+
+// REST API served under /plugins/<plugin id>. Requests must come from a
+// logged in user, whose ID the server passes in the Mattermost-User-Id
+// header, and only return receipts of channels the user can read.
+const (
+	apiPrefix    = "/api/v1"
+	userIDHeader = "Mattermost-User-Id"
+
+	// defaultAPIPageSize and maxAPIPageSize bound the receipts of a list page
+	defaultAPIPageSize = 50
+	maxAPIPageSize     = 200
+	// apiDateLayout is the layout of date-only filters, in Istanbul time
+	apiDateLayout = "2006-01-02"
+)
+
+// receiptListResponse is a page of receipts returned by the list endpoint
+type receiptListResponse struct {
+	Receipts []*Transaction `json:"receipts"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PerPage  int            `json:"per_page"`
+}
+
+// deliveryListResponse is the webhook delivery log
+type deliveryListResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
+
+// apiError is the body of an error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// newAPIRouter registers the REST API endpoints of the plugin
+func (p *Plugin) newAPIRouter() *http.ServeMux {
+	router := http.NewServeMux()
+	router.HandleFunc("GET "+apiPrefix+"/receipts", p.requireUser(p.handleListReceipts))
+	router.HandleFunc("GET "+apiPrefix+"/receipts/{id}", p.requireUser(p.handleGetReceipt))
+	router.HandleFunc("GET "+apiPrefix+"/export", p.requireUser(p.handleExport))
+	router.HandleFunc("GET "+apiPrefix+"/webhooks/deliveries", p.requireUser(p.handleListDeliveries))
+	return router
+}
+
+// ServeHTTP handles the HTTP requests sent to the plugin
+func (p *Plugin) ServeHTTP(_ *plugin.Context, w http.ResponseWriter, r *http.Request) {
+	p.router.ServeHTTP(w, r)
+}
+
+// requireUser rejects requests that do not come from a logged in user
+func (p *Plugin) requireUser(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(userIDHeader) == "" {
+			writeAPIError(w, http.StatusUnauthorized, "not authenticated")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// handleListReceipts lists the receipts matching the query parameters in the
+// channels the user can read, newest first
+func (p *Plugin) handleListReceipts(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(userIDHeader)
+	params := r.URL.Query()
+
+	query, err := parseReceiptQuery(params)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, perPage, err := parsePagination(params)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.ChannelID != "" && !p.API.HasPermissionToChannel(userID, query.ChannelID, model.PermissionReadChannel) {
+		writeAPIError(w, http.StatusForbidden, "no access to channel")
+		return
+	}
+
+	transactions, err := p.store.List(query)
+	if err != nil {
+		p.API.LogError("Failed to list transactions for API", "userId", userID, "error", err.Error())
+		writeAPIError(w, http.StatusInternalServerError, "failed to read receipts")
+		return
+	}
+	transactions = p.readableTransactions(userID, transactions)
+
+	response := receiptListResponse{Receipts: []*Transaction{}, Total: len(transactions), Page: page, PerPage: perPage}
+	if start := page * perPage; start < len(transactions) {
+		end := start + perPage
+		if end > len(transactions) {
+			end = len(transactions)
+		}
+		response.Receipts = transactions[start:end]
+	}
+	writeAPIJSON(w, http.StatusOK, response)
+}
+
+// handleGetReceipt returns a single receipt. Receipts of channels the user
+// cannot read are reported as not found.
+func (p *Plugin) handleGetReceipt(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(userIDHeader)
+	id := r.PathValue("id")
+
+	tx, err := p.store.Get(id)
+	if err != nil {
+		p.API.LogError("Failed to get transaction for API", "id", id, "error", err.Error())
+		writeAPIError(w, http.StatusInternalServerError, "failed to read receipt")
+		return
+	}
+	if tx == nil || !p.API.HasPermissionToChannel(userID, tx.ChannelID, model.PermissionReadChannel) {
+		writeAPIError(w, http.StatusNotFound, "receipt not found")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, tx)
+}
+
+// handleExport returns a CSV or XLSX file of the receipts of a channel. It
+// takes the filters of the list endpoint, with channel_id required, and the
+// format parameter, csv by default.
+func (p *Plugin) handleExport(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(userIDHeader)
+	params := r.URL.Query()
+
+	query, err := parseReceiptQuery(params)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if query.ChannelID == "" {
+		writeAPIError(w, http.StatusBadRequest, "channel_id parameter is required")
+		return
+	}
+	format := strings.ToLower(params.Get("format"))
+	if format == "" {
+		format = exportFormatCSV
+	}
+	if _, ok := exportContentTypes[format]; !ok {
+		writeAPIError(w, http.StatusBadRequest, errInvalidParam("format").Error())
+		return
+	}
+	if !p.API.HasPermissionToChannel(userID, query.ChannelID, model.PermissionReadChannel) {
+		writeAPIError(w, http.StatusForbidden, "no access to channel")
+		return
+	}
+
+	transactions, err := p.store.List(query)
+	if err != nil {
+		p.API.LogError("Failed to list transactions for export", "channelId", query.ChannelID, "error", err.Error())
+		writeAPIError(w, http.StatusInternalServerError, "failed to read receipts")
+		return
+	}
+	data, err := p.buildExport(transactions, format)
+	if err != nil {
+		p.API.LogError("Failed to build export", "format", format, "error", err.Error())
+		writeAPIError(w, http.StatusInternalServerError, "failed to build export")
+		return
+	}
+
+	from, to := query.From, query.To
+	if to.IsZero() {
+		to = time.Now().In(istanbul)
+	}
+	if from.IsZero() {
+		from = to
+		if len(transactions) > 0 {
+			from = transactions[len(transactions)-1].Time()
+		}
+	}
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exportFileName(format, from.In(istanbul), to.In(istanbul))))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// handleListDeliveries returns the webhook delivery log, newest first, to
+// system admins
+func (p *Plugin) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get(userIDHeader)
+	if !p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		writeAPIError(w, http.StatusForbidden, "system admin permission required")
+		return
+	}
+
+	deliveries, err := p.store.ListDeliveries()
+	if err != nil {
+		p.API.LogError("Failed to list webhook deliveries", "error", err.Error())
+		writeAPIError(w, http.StatusInternalServerError, "failed to read deliveries")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, deliveryListResponse{Deliveries: deliveries})
+}
+
+// readableTransactions keeps the transactions of the channels the user can
+// read, checking every channel once
+func (p *Plugin) readableTransactions(userID string, transactions []*Transaction) []*Transaction {
+	readable := map[string]bool{}
+	result := make([]*Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		allowed, checked := readable[tx.ChannelID]
+		if !checked {
+			allowed = p.API.HasPermissionToChannel(userID, tx.ChannelID, model.PermissionReadChannel)
+			readable[tx.ChannelID] = allowed
+		}
+		if allowed {
+			result = append(result, tx)
+		}
+	}
+	return result
+}
+
+// parseReceiptQuery builds the transaction query from the channel_id, from,
+// to, bank, counterparty, type, reference, min_amount and max_amount
+// parameters
+func parseReceiptQuery(params url.Values) (TransactionQuery, error) {
+	get := func(name string) string {
+		return strings.TrimSpace(params.Get(name))
+	}
+
+	query := TransactionQuery{
+		ChannelID:    get("channel_id"),
+		Bank:         get("bank"),
+		Counterparty: get("counterparty"),
+		Reference:    get("reference"),
+		Type:         TransactionType(get("type")),
+	}
+
+	var err error
+	if query.From, err = parseAPITime(get("from"), false); err != nil {
+		return query, errInvalidParam("from")
+	}
+	if query.To, err = parseAPITime(get("to"), true); err != nil {
+		return query, errInvalidParam("to")
+	}
+	for _, param := range []struct {
+		name   string
+		target *Amount
+	}{{"min_amount", &query.MinAmount}, {"max_amount", &query.MaxAmount}} {
+		if value := get(param.name); value != "" {
+			amount, ok := parseAmount(value)
+			if !ok {
+				return query, errInvalidParam(param.name)
+			}
+			*param.target = amount
+		}
+	}
+	return query, nil
+}
+
+// parseAPITime parses a date (YYYY-MM-DD, Istanbul time) or an RFC 3339
+// timestamp. A date used as the end of a range includes the whole day.
+func parseAPITime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(apiDateLayout, value, istanbul)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
+}
+
+// parsePagination reads the zero-based page and the per_page parameters
+func parsePagination(params url.Values) (page, perPage int, err error) {
+	page, perPage = 0, defaultAPIPageSize
+	if params.Has("page") {
+		if page, err = strconv.Atoi(params.Get("page")); err != nil || page < 0 {
+			return 0, 0, errInvalidParam("page")
+		}
+	}
+	if params.Has("per_page") {
+		if perPage, err = strconv.Atoi(params.Get("per_page")); err != nil || perPage <= 0 {
+			return 0, 0, errInvalidParam("per_page")
+		}
+		if perPage > maxAPIPageSize {
+			perPage = maxAPIPageSize
+		}
+	}
+	return page, perPage, nil
+}
+
+// errInvalidParam returns the error of a query parameter that could not be
+// parsed
+func errInvalidParam(name string) error {
+	return fmt.Errorf("invalid %s parameter", name)
+}
+
+// writeAPIJSON writes value as the JSON body of the response
+func writeAPIJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeAPIError writes an error response
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, apiError{Error: message})
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestServeHTTP(t *testing.T) {
+	p, api := newCommandTestPlugin(t)
+	p.router = p.newAPIRouter()
+	api.On("HasPermissionToChannel", "user", "finance", model.PermissionReadChannel).Return(true)
+	api.On("HasPermissionToChannel", "user", "board", model.PermissionReadChannel).Return(false)
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		userID   string
+		status   int
+		expected []string // IDs of the returned receipts, in order
+		total    int
+	}{
+		{name: "requires a user", path: "/api/v1/receipts", status: http.StatusUnauthorized},
+		{name: "lists readable channels only", path: "/api/v1/receipts", userID: "user", status: http.StatusOK,
+			expected: []string{"file2_0", "file1_0"}, total: 2},
+		{name: "unreadable channel", path: "/api/v1/receipts?channel_id=board", userID: "user", status: http.StatusForbidden},
+		{name: "by channel and bank", path: "/api/v1/receipts?channel_id=finance&bank=halkbank", userID: "user",
+			status: http.StatusOK, expected: []string{"file1_0"}, total: 1},
+		{name: "by amount range", path: "/api/v1/receipts?min_amount=200&max_amount=1.000,00", userID: "user",
+			status: http.StatusOK, expected: []string{"file2_0"}, total: 1},
+		{name: "by counterparty", path: "/api/v1/receipts?counterparty=abc%20%C5%9Eirketi", userID: "user",
+			status: http.StatusOK, expected: []string{"file1_0"}, total: 1},
+		{name: "second page", path: "/api/v1/receipts?per_page=1&page=1", userID: "user", status: http.StatusOK,
+			expected: []string{"file1_0"}, total: 2},
+		{name: "page past the end", path: "/api/v1/receipts?page=5", userID: "user", status: http.StatusOK,
+			expected: []string{}, total: 2},
+		{name: "invalid date", path: "/api/v1/receipts?from=01.07.2025", userID: "user", status: http.StatusBadRequest},
+		{name: "invalid amount", path: "/api/v1/receipts?min_amount=abc", userID: "user", status: http.StatusBadRequest},
+		{name: "invalid page size", path: "/api/v1/receipts?per_page=0", userID: "user", status: http.StatusBadRequest},
+		{name: "single receipt", path: "/api/v1/receipts/file2_0", userID: "user", status: http.StatusOK,
+			expected: []string{"file2_0"}},
+		{name: "receipt of an unreadable channel", path: "/api/v1/receipts/file3_0", userID: "user", status: http.StatusNotFound},
+		{name: "missing receipt", path: "/api/v1/receipts/missing", userID: "user", status: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPost, path: "/api/v1/receipts", userID: "user",
+			status: http.StatusMethodNotAllowed},
+		{name: "unknown path", path: "/api/v2/receipts", userID: "user", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, tt.path, nil)
+			if tt.userID != "" {
+				r.Header.Set(userIDHeader, tt.userID)
+			}
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("ServeHTTP(%s) status = %d, want %d: %s", tt.path, w.Code, tt.status, w.Body)
+			}
+			if tt.expected == nil {
+				return
+			}
+
+			var ids []string
+			if strings.Contains(tt.path, "/receipts/") {
+				var tx Transaction
+				if err := json.Unmarshal(w.Body.Bytes(), &tx); err != nil {
+					t.Fatalf("json.Unmarshal() error = %v", err)
+				}
+				ids = append(ids, tx.ID)
+			} else {
+				var response receiptListResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("json.Unmarshal() error = %v", err)
+				}
+				if response.Total != tt.total {
+					t.Errorf("ServeHTTP(%s) total = %d, want %d", tt.path, response.Total, tt.total)
+				}
+				ids = []string{}
+				for _, tx := range response.Receipts {
+					ids = append(ids, tx.ID)
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("ServeHTTP(%s) = %v, want %v", tt.path, ids, tt.expected)
+			}
+		})
+	}
+}
+
+func TestServeHTTPExport(t *testing.T) {
+	p, api := newCommandTestPlugin(t)
+	p.router = p.newAPIRouter()
+	api.On("HasPermissionToChannel", "user", "finance", model.PermissionReadChannel).Return(true)
+	api.On("HasPermissionToChannel", "user", "board", model.PermissionReadChannel).Return(false)
+
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{name: "csv by default", path: "/api/v1/export?channel_id=finance", status: http.StatusOK,
+			contentType: exportContentTypes[exportFormatCSV], contains: "XYZ Ltd."},
+		{name: "xlsx", path: "/api/v1/export?channel_id=finance&format=xlsx&from=2020-01-01", status: http.StatusOK,
+			contentType: exportContentTypes[exportFormatXLSX], contains: "PK"},
+		{name: "channel is required", path: "/api/v1/export", status: http.StatusBadRequest},
+		{name: "unknown format", path: "/api/v1/export?channel_id=finance&format=pdf", status: http.StatusBadRequest},
+		{name: "unreadable channel", path: "/api/v1/export?channel_id=board", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Header.Set(userIDHeader, "user")
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("ServeHTTP(%s) status = %d, want %d: %s", tt.path, w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.contentType)
+			}
+			if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, `attachment; filename="dekontlar_`) {
+				t.Errorf("Content-Disposition = %q", disposition)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("ServeHTTP(%s) body does not contain %q", tt.path, tt.contains)
+			}
+		})
+	}
+}
+
+func TestServeHTTPDeliveries(t *testing.T) {
+	p, api := newCommandTestPlugin(t)
+	p.router = p.newAPIRouter()
+	api.On("HasPermissionTo", "admin", model.PermissionManageSystem).Return(true)
+	api.On("HasPermissionTo", "user", model.PermissionManageSystem).Return(false)
+
+	for _, id := range []string{"delivery1", "delivery2"} {
+		if err := p.store.QueueDelivery(&WebhookDelivery{ID: id, URL: "https://erp.example.com/hook", Status: deliveryPending}); err != nil {
+			t.Fatalf("QueueDelivery() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		userID   string
+		status   int
+		expected []string
+	}{
+		{name: "newest first for admins", userID: "admin", status: http.StatusOK, expected: []string{"delivery2", "delivery1"}},
+		{name: "forbidden for users", userID: "user", status: http.StatusForbidden},
+		{name: "requires a user", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/webhooks/deliveries", nil)
+			if tt.userID != "" {
+				r.Header.Set(userIDHeader, tt.userID)
+			}
+			w := httptest.NewRecorder()
+			p.ServeHTTP(nil, w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var response deliveryListResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			ids := make([]string, len(response.Deliveries))
+			for i, delivery := range response.Deliveries {
+				ids[i] = delivery.ID
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("deliveries = %v, want %v", ids, tt.expected)
+			}
+		})
+	}
+}
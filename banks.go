@@ -0,0 +1,208 @@
+package main
+
+import "regexp"
+
+// Shared regex fragments for bank patterns. Turkish dotted and dotless I
+// do not case-fold onto each other, so both are listed explicitly.
+const (
+	separatorPattern = `[:\-=>\s]*`
+	valuePattern     = `(.+?)(?:\n|$)`
+	numberPattern    = `([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*` + currencyPattern + `?`
+	// ratePattern captures an exchange rate, which has up to six fraction digits
+	ratePattern = `([0-9]+(?:[.,][0-9]{3})*[.,][0-9]{1,6})`
+	// referencePattern captures a reference number containing at least one digit
+	referencePattern = `([A-Z0-9/\-]*[0-9][A-Z0-9/\-]*)`
+	// numberSuffix is the "No" or "Numarası" following a reference label
+	numberSuffix = `\s*(?:NO\b\.?|NUMARAS[Iı])`
+)
+
+// fieldRegex compiles a case-insensitive pattern capturing the text after label
+func fieldRegex(label string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)` + label + separatorPattern + valuePattern)
+}
+
+// amountRegex compiles a case-insensitive pattern capturing the number after label
+func amountRegex(label string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)` + label + `\s*(?:\(` + currencyPattern + `\))?[:\-=>\s]*(?:.*?)?` + numberPattern)
+}
+
+// dateRegex compiles a case-insensitive pattern capturing the date printed
+// after label on the same line
+func dateRegex(label string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)` + label + `[^\n0-9]*` + anyDatePattern)
+}
+
+// referenceRegex compiles a case-insensitive pattern capturing the reference
+// number after label
+func referenceRegex(label string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)` + label + separatorPattern + referencePattern)
+}
+
+// signal compiles a case-insensitive detection signal
+func signal(pattern string, weight float64) detectSignal {
+	return detectSignal{re: regexp.MustCompile(`(?i)` + pattern), weight: weight}
+}
+
+// genericPatterns returns the bank independent field patterns
+func genericPatterns() fieldPatterns {
+	return fieldPatterns{
+		recipient:   []*regexp.Regexp{fieldRegex(`AL[Iı]C[Iı](?:\s*AD\s*SOYAD\s*/?\s*UNVAN)?`)},
+		sender:      []*regexp.Regexp{fieldRegex(`G[ÖO]NDEREN`)},
+		description: []*regexp.Regexp{fieldRegex(`A[ÇC][Iı]KLAMA`)},
+		amount: []*regexp.Regexp{
+			amountRegex(`(?:[İIıi][ŞS]LEM\s*TUTARI|TUTAR[IİĞ]?|HAVALE\s*TUTARI|G[İIıi]DEN\s*EFT\s*TUTARI|EFT\s*TUTARI|` +
+				`TRANSFER\s*TUTARI|PARA\s*TUTARI|M[İIıi]KTAR)`),
+			// No labelled amount, take the first value followed by a currency
+			regexp.MustCompile(`(?i)([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*` + currencyPattern),
+		},
+		date:      []*regexp.Regexp{dateRegex(`[İIıi][ŞS]LEM\s*TAR[İIıi]H[İIıi]`)},
+		reference: referencePatterns,
+	}
+}
+
+// Patterns shared by every bank. Foreign currency receipts print the lira
+// equivalent and the applied rate next to the amount.
+var (
+	localAmountPatterns = []*regexp.Regexp{
+		amountRegex(`(?:TL\s*KAR[ŞS][Iı]L[Iı][ĞG][Iı]|KAR[ŞS][Iı]L[Iı]K\s*TUTAR[Iı]?|TL\s*TUTARI)`),
+	}
+	// datePatterns find the transaction date when the bank patterns do not,
+	// preferring labelled dates over the first date in the text
+	datePatterns = []*regexp.Regexp{
+		dateRegex(`[İIıi][ŞS]LEM\s*TAR[İIıi]H[İIıi]`),
+		dateRegex(`TAR[İIıi]H`),
+		regexp.MustCompile(`(?i)` + anyDatePattern),
+	}
+	// referencePatterns find the reference number when the bank patterns do
+	// not, preferring the reference over the query and receipt numbers
+	referencePatterns = []*regexp.Regexp{
+		referenceRegex(`[İIıi][ŞS]LEM\s*REFERANS(?:[Iı]|` + numberSuffix + `)`),
+		referenceRegex(`REFERANS(?:[Iı]|` + numberSuffix + `)`),
+		referenceRegex(`SORGU` + numberSuffix),
+		referenceRegex(`DEKONT` + numberSuffix),
+		referenceRegex(`[İIıi][ŞS]LEM` + numberSuffix),
+		referenceRegex(`F[İIıi][ŞS]` + numberSuffix),
+	}
+	// Charges printed next to the transferred amount. Their labels often
+	// contain TUTAR as well, so they are masked before the amount is read.
+	feePatterns = []*regexp.Regexp{
+		amountRegex(`(?:TOPLAM\s*)?MASRAF(?:\s*TUTARI)?`),
+		amountRegex(`(?:[İIıi][ŞS]LEM|EFT|HAVALE|HAVALE/EFT)\s*[ÜU]CRET[İIıi]`),
+	}
+	commissionPatterns = []*regexp.Regexp{
+		amountRegex(`KOM[İIıi]SYON(?:\s*TUTARI)?`),
+	}
+	taxPatterns = []*regexp.Regexp{
+		amountRegex(`B\.?\s*S\.?\s*M\.?\s*V\.?(?:\s*TUTARI)?`),
+		amountRegex(`MUAMELE(?:LER[İIıi])?\s*VERG[İIıi]S[İIıi]`),
+	}
+	totalPatterns = []*regexp.Regexp{
+		amountRegex(`TOPLAM\s*(?:[İIıi][ŞS]LEM\s*)?TUTAR[Iı]?`),
+		amountRegex(`(?:HESAPTAN\s*)?[ÇC]EK[İIıi]LEN\s*TOPLAM(?:\s*TUTAR)?`),
+		amountRegex(`GENEL\s*TOPLAM`),
+	}
+	exchangeRatePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)(?:D[ÖO]V[İIıi]Z\s*KURU|[İIıi][ŞS]LEM\s*KURU|UYGULANAN\s*KUR|\bKUR\b)\s*(?:\(TL\))?[:\-=>\s]*` + ratePattern),
+	}
+)
+
+// newGenericParser returns the fallback parser used when no bank is detected
+func newGenericParser() BankParser {
+	return &patternParser{
+		name:     "Generic",
+		patterns: genericPatterns(),
+		generic:  true,
+	}
+}
+
+// newProfileParser returns a parser that identifies a bank by its profile
+// and reads its receipts with the generic patterns
+func newProfileParser(profile bankProfile) BankParser {
+	return &patternParser{
+		name:     profile.name,
+		signals:  profile.signals(),
+		patterns: genericPatterns(),
+		generic:  true,
+	}
+}
+
+// newVakifBankParser returns the parser for VakıfBank receipts
+func newVakifBankParser() BankParser {
+	return &patternParser{
+		name: vakifBankProfile.name,
+		signals: append(vakifBankProfile.signals(),
+			signal(`AL[Iı]C[Iı]\s*AD\s*SOYAD\s*/\s*UNVAN`, layoutWeight),
+			signal(`G[ÖO]NDEREN\s*AD\s*SOYAD\s*/\s*UNVAN`, layoutWeight),
+			signal(`[İIıi][ŞS]LEM\s*A[ÇC][Iı]KLAMASI`, layoutWeight),
+		),
+		patterns: fieldPatterns{
+			recipient:   []*regexp.Regexp{fieldRegex(`AL[Iı]C[Iı](?:\s*AD\s*SOYAD\s*/?\s*UNVAN)?`)},
+			sender:      []*regexp.Regexp{fieldRegex(`G[ÖO]NDEREN(?:\s*AD\s*SOYAD\s*/?\s*UNVAN)?`)},
+			description: []*regexp.Regexp{fieldRegex(`(?:[İIıi][ŞS]LEM\s*)?A[ÇC][Iı]KLAMA(?:S[Iı])?`)},
+			amount:      []*regexp.Regexp{amountRegex(`[İIıi][ŞS]LEM\s*TUTARI`)},
+			date:        []*regexp.Regexp{dateRegex(`[İIıi][ŞS]LEM\s*TAR[İIıi]H[İIıi]`)},
+			reference:   []*regexp.Regexp{referenceRegex(`REFERANS` + numberSuffix)},
+		},
+	}
+}
+
+// newYapiKrediParser returns the parser for Yapı Kredi receipts
+func newYapiKrediParser() BankParser {
+	return &patternParser{
+		name: yapiKrediProfile.name,
+		signals: append(yapiKrediProfile.signals(),
+			signal(`AL[Iı]C[Iı]\s*AD[Iı]`, layoutWeight),
+			signal(`G[ÖO]NDEREN\s*AD[Iı]\s*SOYAD`, layoutWeight),
+			signal(`G[İIıi]DEN\s*EFT\s*TUTARI`, layoutWeight),
+		),
+		patterns: fieldPatterns{
+			recipient:   []*regexp.Regexp{fieldRegex(`AL[Iı]C[Iı](?:\s*AD[Iı])?`)},
+			sender:      []*regexp.Regexp{fieldRegex(`G[ÖO]NDEREN(?:\s*AD[Iı]\s*SOYAD[Iı]?)?`)},
+			description: []*regexp.Regexp{fieldRegex(`A[ÇC][Iı]KLAMA`)},
+			amount: []*regexp.Regexp{
+				amountRegex(`G[İIıi]DEN\s*EFT\s*TUTARI`),
+				amountRegex(`TUTAR[Iı]?`),
+			},
+			reference: []*regexp.Regexp{
+				referenceRegex(`DEKONT` + numberSuffix),
+				referenceRegex(`[İIıi][ŞS]LEM` + numberSuffix),
+			},
+		},
+	}
+}
+
+// newKuveytTurkParser returns the parser for Kuveyt Türk receipts
+func newKuveytTurkParser() BankParser {
+	return &patternParser{
+		name: kuveytTurkProfile.name,
+		signals: append(kuveytTurkProfile.signals(),
+			signal(`G[ÖO]NDEREN\s*K[İIıi][ŞS][İIıi]`, layoutWeight),
+			signal(`G[ÖO]NDER[İIıi]LEN\s*IBAN`, layoutWeight),
+		),
+		patterns: fieldPatterns{
+			recipient:   []*regexp.Regexp{fieldRegex(`AL[Iı]C[Iı]`)},
+			sender:      []*regexp.Regexp{fieldRegex(`G[ÖO]NDEREN(?:\s*K[İIıi][ŞS][İIıi])?`)},
+			description: []*regexp.Regexp{fieldRegex(`A[ÇC][Iı]KLAMA`)},
+			amount:      []*regexp.Regexp{amountRegex(`TUTAR`)},
+			reference:   []*regexp.Regexp{referenceRegex(`SORGU` + numberSuffix)},
+		},
+	}
+}
+
+// newHalkBankParser returns the parser for HalkBank receipts
+func newHalkBankParser() BankParser {
+	return &patternParser{
+		name: halkBankProfile.name,
+		signals: append(halkBankProfile.signals(),
+			signal(`[İIıi][ŞS]LEM\s*TUTARI\s*\(TL\)`, layoutWeight),
+		),
+		patterns: fieldPatterns{
+			recipient:   []*regexp.Regexp{fieldRegex(`AL[Iı]C[Iı]`)},
+			sender:      []*regexp.Regexp{fieldRegex(`G[ÖO]NDEREN`)},
+			description: []*regexp.Regexp{fieldRegex(`A[ÇC][Iı]KLAMA`)},
+			amount:      []*regexp.Regexp{amountRegex(`[İIıi][ŞS]LEM\s*TUTARI\s*\(TL\)`)},
+			date:        []*regexp.Regexp{dateRegex(`[İIıi][ŞS]LEM\s*TAR[İIıi]H[İIıi]`)},
+			reference:   []*regexp.Regexp{referenceRegex(`[İIıi][ŞS]LEM\s*REFERANS` + numberSuffix)},
+		},
+	}
+}
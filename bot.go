@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Bot account the plugin posts its output as
+const (
+	botUsername    = "dekont-bot"
+	botDisplayName = "Dekont Parser"
+	botDescription = "Posts transaction details parsed from PDF bank receipts. Created by the PDF Dekont Parser plugin."
+)
+
+// ensureBotUser returns the user ID of the plugin bot, creating the bot
+// on first activation and reactivating it if it was disabled
+func (p *Plugin) ensureBotUser() (string, error) {
+	user, appErr := p.API.GetUserByUsername(botUsername)
+	if appErr == nil && user != nil {
+		if !user.IsBot {
+			return "", fmt.Errorf("username %q is taken by a user that is not a bot", botUsername)
+		}
+		if user.DeleteAt != 0 {
+			if _, appErr = p.API.UpdateBotActive(user.Id, true); appErr != nil {
+				return "", appErr
+			}
+		}
+		return user.Id, nil
+	}
+
+	bot, appErr := p.API.CreateBot(&model.Bot{
+		Username:    botUsername,
+		DisplayName: botDisplayName,
+		Description: botDescription,
+	})
+	if appErr != nil {
+		return "", appErr
+	}
+
+	return bot.UserId, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEnsureBotUser(t *testing.T) {
+	notFound := model.NewAppError("GetUserByUsername", "not_found", nil, "", http.StatusNotFound)
+
+	t.Run("creates the bot", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetUserByUsername", botUsername).Return(nil, notFound)
+		api.On("CreateBot", mock.MatchedBy(func(bot *model.Bot) bool {
+			return bot.Username == botUsername
+		})).Return(&model.Bot{UserId: "bot"}, nil)
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		botUserID, err := p.ensureBotUser()
+		if err != nil || botUserID != "bot" {
+			t.Errorf("ensureBotUser() = %q, %v, want %q", botUserID, err, "bot")
+		}
+		api.AssertExpectations(t)
+	})
+
+	t.Run("reuses and reactivates the existing bot", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetUserByUsername", botUsername).Return(&model.User{Id: "bot", IsBot: true, DeleteAt: 1}, nil)
+		api.On("UpdateBotActive", "bot", true).Return(&model.Bot{UserId: "bot"}, nil)
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		botUserID, err := p.ensureBotUser()
+		if err != nil || botUserID != "bot" {
+			t.Errorf("ensureBotUser() = %q, %v, want %q", botUserID, err, "bot")
+		}
+		api.AssertExpectations(t)
+	})
+
+	t.Run("rejects a human user with the bot username", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetUserByUsername", botUsername).Return(&model.User{Id: "human"}, nil)
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		if _, err := p.ensureBotUser(); err == nil {
+			t.Error("ensureBotUser() error = nil, want error")
+		}
+	})
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+)
+
+const (
+	commandTrigger = "dekont"
+
+	// defaultListCount and maxListCount bound the rows of /dekont list
+	defaultListCount = 10
+	maxListCount     = 50
+	// defaultSummaryDays is the period of /dekont summary without argument
+	defaultSummaryDays = 30
+)
+
+const commandHelpText = "###### /dekont komutları\n" +
+	"- `/dekont parse <gönderi bağlantısı>` - Gönderideki PDF dekontları işler ve sonucu yalnızca size gösterir\n" +
+	"- `/dekont reprocess <gönderi bağlantısı>` - Gönderideki dekontları yeniden işleyip yayınlar\n" +
+	"- `/dekont list [adet]` - Bu kanalda işlenen son dekontları listeler\n" +
+	"- `/dekont summary [gün]` - Bu kanaldaki dekontların toplamlarını gösterir\n" +
+	"- `/dekont find <referans no>` - Referans, sorgu veya dekont numarasıyla dekont arar\n" +
+	"- `/dekont export [csv|xlsx] [gün | başlangıç bitiş]` - Bu kanaldaki dekontların dökümünü dosya olarak kanala ekler\n" +
+	"- `/dekont banks` - Desteklenen bankaları listeler\n" +
+	"- `/dekont help` - Bu yardım metnini gösterir"
+
+// getCommand returns the /dekont slash command registered on activation
+func getCommand() *model.Command {
+	return &model.Command{
+		Trigger:          commandTrigger,
+		DisplayName:      "Dekont",
+		Description:      "PDF banka dekontlarını işler ve listeler",
+		AutoComplete:     true,
+		AutoCompleteDesc: "Kullanılabilir komutlar: parse, reprocess, list, summary, find, export, banks, help",
+		AutoCompleteHint: "[komut]",
+		AutocompleteData: getAutocompleteData(),
+	}
+}
+
+// getAutocompleteData describes the subcommands for the autocomplete menu
+func getAutocompleteData() *model.AutocompleteData {
+	dekont := model.NewAutocompleteData(commandTrigger, "[komut]", "PDF banka dekontlarını işler ve listeler")
+
+	parse := model.NewAutocompleteData("parse", "<gönderi bağlantısı>", "Gönderideki PDF dekontları işler ve sonucu yalnızca size gösterir")
+	parse.AddTextArgument("Dekontun eklendiği gönderinin bağlantısı", "<gönderi bağlantısı>", "")
+	dekont.AddCommand(parse)
+
+	reprocess := model.NewAutocompleteData("reprocess", "<gönderi bağlantısı>", "Gönderideki dekontları yeniden işleyip yayınlar")
+	reprocess.AddTextArgument("Dekontun eklendiği gönderinin bağlantısı", "<gönderi bağlantısı>", "")
+	dekont.AddCommand(reprocess)
+
+	list := model.NewAutocompleteData("list", "[adet]", "Bu kanalda işlenen son dekontları listeler")
+	list.AddTextArgument("Listelenecek dekont sayısı", "[adet]", `^[0-9]*$`)
+	dekont.AddCommand(list)
+
+	summary := model.NewAutocompleteData("summary", "[gün]", "Bu kanaldaki dekontların toplamlarını gösterir")
+	summary.AddTextArgument("Özetlenecek gün sayısı", "[gün]", `^[0-9]*$`)
+	dekont.AddCommand(summary)
+
+	find := model.NewAutocompleteData("find", "<referans no>", "Referans, sorgu veya dekont numarasıyla dekont arar")
+	find.AddTextArgument("Dekonttaki referans, sorgu veya dekont numarası", "<referans no>", "")
+	dekont.AddCommand(find)
+
+	export := model.NewAutocompleteData("export", "[csv|xlsx] [gün | başlangıç bitiş]", "Bu kanaldaki dekontların dökümünü dosya olarak kanala ekler")
+	export.AddStaticListArgument("Dosya biçimi", false, []model.AutocompleteListItem{
+		{Item: exportFormatCSV, HelpText: "Virgülle ayrılmış değerler"},
+		{Item: exportFormatXLSX, HelpText: "Excel çalışma kitabı"},
+	})
+	export.AddTextArgument("Gün sayısı veya başlangıç ve bitiş tarihleri, örneğin 01.07.2025 31.07.2025", "[gün | başlangıç bitiş]", "")
+	dekont.AddCommand(export)
+
+	dekont.AddCommand(model.NewAutocompleteData("banks", "", "Desteklenen bankaları listeler"))
+	dekont.AddCommand(model.NewAutocompleteData("help", "", "Yardım metnini gösterir"))
+
+	return dekont
+}
+
+// ExecuteCommand handles the /dekont slash command
+func (p *Plugin) ExecuteCommand(_ *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	fields := strings.Fields(args.Command)
+	subcommand, params := "help", []string{}
+	if len(fields) > 1 {
+		subcommand, params = strings.ToLower(fields[1]), fields[2:]
+	}
+
+	var text string
+	switch subcommand {
+	case "parse":
+		text = p.executeParse(args, params)
+	case "reprocess":
+		text = p.executeReprocess(args, params)
+	case "list":
+		text = p.executeList(args, params)
+	case "summary":
+		text = p.executeSummary(args, params)
+	case "find":
+		text = p.executeFind(args, params)
+	case "export":
+		text = p.executeExport(args, params)
+	case "banks":
+		text = formatSupportedBanks(p.getConfiguration().parserRegistry())
+	case "help":
+		text = commandHelpText
+	default:
+		text = fmt.Sprintf("Bilinmeyen komut: `%s`\n\n%s", subcommand, commandHelpText)
+	}
+
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}, nil
+}
+
+// executeParse parses the PDF attachments of a linked post and shows the
+// result only to the user, without publishing or storing it
+func (p *Plugin) executeParse(args *model.CommandArgs, params []string) string {
+	post, errText := p.linkedPost(args, params)
+	if post == nil {
+		return errText
+	}
+
+	config := p.getConfiguration()
+	results := p.readAttachments(post, config)
+	if len(attachmentReceipts(results)) == 0 {
+		for _, result := range results {
+			if result.err != nil {
+				return config.ErrorNotificationMessage + "\n\n" + formatAttachmentErrors(results)
+			}
+		}
+		return "Gönderide işlenebilir bir PDF dekont bulunamadı."
+	}
+	return formatAttachments(results, config)
+}
+
+// executeReprocess runs the upload processing again for a linked post. Only
+// the author of the post or users who may edit others' posts can use it.
+func (p *Plugin) executeReprocess(args *model.CommandArgs, params []string) string {
+	post, errText := p.linkedPost(args, params)
+	if post == nil {
+		return errText
+	}
+
+	if post.UserId != args.UserId && !p.API.HasPermissionToChannel(args.UserId, post.ChannelId, model.PermissionEditOthersPosts) {
+		return "Bu gönderiyi yeniden işleme yetkiniz yok."
+	}
+
+	failed, err := p.processPost(post)
+	if err != nil {
+		p.API.LogError("Failed to reprocess post", "postId", post.Id, "error", err.Error())
+		return "Gönderi yeniden işlenemedi. " + p.getConfiguration().ErrorNotificationMessage
+	}
+	if len(failed) > 0 {
+		return fmt.Sprintf("%d dosya yeniden işlenemedi. %s\n\n%s", len(failed), p.getConfiguration().ErrorNotificationMessage,
+			formatAttachmentErrors(failed))
+	}
+	return "Gönderi yeniden işlendi."
+}
+
+// linkedPost returns the post referenced by the first parameter if the user
+// can read it, or a message explaining why not
+func (p *Plugin) linkedPost(args *model.CommandArgs, params []string) (*model.Post, string) {
+	if len(params) == 0 {
+		return nil, "Bir gönderi bağlantısı belirtin, örneğin `/dekont parse https://chat.example.com/team/pl/<id>`"
+	}
+
+	postID := parsePostID(params[0])
+	if postID == "" {
+		return nil, fmt.Sprintf("Geçersiz gönderi bağlantısı: `%s`", params[0])
+	}
+
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil || !p.API.HasPermissionToChannel(args.UserId, post.ChannelId, model.PermissionReadChannel) {
+		return nil, "Gönderi bulunamadı veya görüntüleme yetkiniz yok."
+	}
+	if len(post.FileIds) == 0 {
+		return nil, "Gönderide ek dosya yok."
+	}
+	return post, ""
+}
+
+// parsePostID extracts the post ID from a permalink such as
+// https://chat.example.com/team/pl/<id> or from a bare post ID. It returns
+// an empty string when no valid ID is found.
+func parsePostID(link string) string {
+	link = strings.Trim(link, "<>")
+	if i := strings.IndexAny(link, "?#"); i >= 0 {
+		link = link[:i]
+	}
+	link = strings.TrimRight(link, "/")
+
+	postID := link[strings.LastIndex(link, "/")+1:]
+	if !model.IsValidId(postID) {
+		return ""
+	}
+	return postID
+}
+
+// executeList lists the most recent transactions of the channel
+func (p *Plugin) executeList(args *model.CommandArgs, params []string) string {
+	count := defaultListCount
+	if len(params) > 0 {
+		n, err := strconv.Atoi(params[0])
+		if err != nil || n <= 0 {
+			return fmt.Sprintf("Geçersiz adet: `%s`", params[0])
+		}
+		count = n
+		if count > maxListCount {
+			count = maxListCount
+		}
+	}
+
+	transactions, err := p.store.List(TransactionQuery{ChannelID: args.ChannelId})
+	if err != nil {
+		p.API.LogError("Failed to list transactions", "channelId", args.ChannelId, "error", err.Error())
+		return "Dekontlar okunamadı."
+	}
+	if len(transactions) == 0 {
+		return "Bu kanalda henüz işlenmiş dekont yok."
+	}
+
+	total := len(transactions)
+	if total > count {
+		transactions = transactions[:count]
+	}
+	return fmt.Sprintf("**Son %d dekont** (toplam %d)\n\n%s", len(transactions), total, p.formatTransactionList(transactions, p.getConfiguration()))
+}
+
+// executeSummary totals the transactions of the channel over the last days
+func (p *Plugin) executeSummary(args *model.CommandArgs, params []string) string {
+	days := defaultSummaryDays
+	if len(params) > 0 {
+		n, err := strconv.Atoi(params[0])
+		if err != nil || n <= 0 {
+			return fmt.Sprintf("Geçersiz gün sayısı: `%s`", params[0])
+		}
+		days = n
+	}
+
+	now := time.Now().In(istanbul)
+	transactions, err := p.store.List(TransactionQuery{
+		ChannelID: args.ChannelId,
+		From:      truncateDay(now.AddDate(0, 0, 1-days)),
+		To:        now,
+	})
+	if err != nil {
+		p.API.LogError("Failed to summarize transactions", "channelId", args.ChannelId, "error", err.Error())
+		return "Dekontlar okunamadı."
+	}
+
+	return formatSummary(transactions, days, p.getConfiguration())
+}
+
+// executeFind looks up transactions by reference number in every channel the
+// user can read
+func (p *Plugin) executeFind(args *model.CommandArgs, params []string) string {
+	if len(params) == 0 {
+		return "Kullanım: `/dekont find <referans no>`"
+	}
+	reference := strings.Join(params, "")
+
+	transactions, err := p.store.List(TransactionQuery{Reference: reference})
+	if err != nil {
+		p.API.LogError("Failed to find transactions", "reference", reference, "error", err.Error())
+		return "Dekontlar okunamadı."
+	}
+
+	var readable []*Transaction
+	for _, tx := range transactions {
+		if p.API.HasPermissionToChannel(args.UserId, tx.ChannelID, model.PermissionReadChannel) {
+			readable = append(readable, tx)
+		}
+	}
+	if len(readable) == 0 {
+		return fmt.Sprintf("`%s` referans numaralı dekont bulunamadı.", normalizeReference(reference))
+	}
+	return fmt.Sprintf("**%s referans numaralı dekontlar**\n\n%s", normalizeReference(reference),
+		p.formatTransactionList(readable, p.getConfiguration()))
+}
+
+// formatTransactionList renders stored transactions as a markdown table with
+// links to their posts
+func (p *Plugin) formatTransactionList(transactions []*Transaction, config *Configuration) string {
+	var result strings.Builder
+
+	result.WriteString("| Tarih | Banka | Tür | Referans | Gönderen | Alıcı | Tutar | Gönderi |\n")
+	result.WriteString("|---|---|---|---|---|---|---:|---|\n")
+	for _, tx := range transactions {
+		receipt := tx.Receipt
+		if receipt == nil {
+			receipt = &Receipt{}
+		}
+		result.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s | %s | [Aç](%s) |\n",
+			tx.Time().Format("02.01.2006"),
+			escapeTableCell(receipt.Bank),
+			formatType(receipt.Type),
+			escapeTableCell(receipt.ReferenceNumber),
+			escapeTableCell(receipt.Sender),
+			escapeTableCell(receipt.Recipient),
+			escapeTableCell(formatAmount(receipt, config)),
+			p.permalink(tx.PostID)))
+	}
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// formatSummary renders the number of transactions with their totals per
+// currency and their count per bank
+func formatSummary(transactions []*Transaction, days int, config *Configuration) string {
+	if len(transactions) == 0 {
+		return fmt.Sprintf("Son %d günde bu kanalda işlenmiş dekont yok.", days)
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("**Son %d gün: %d dekont**\n", days, len(transactions)))
+
+	totals := totalsByCurrency(transactions)
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	if len(currencies) > 0 {
+		result.WriteString("\n**Toplam Tutar**\n")
+		for _, currency := range currencies {
+			result.WriteString(fmt.Sprintf("- %s\n", formatMoney(totals[currency], currency, config.AmountLocale)))
+		}
+	}
+
+	banks := map[string]int{}
+	types := map[string]int{}
+	for _, tx := range transactions {
+		bank := "Tespit edilemedi"
+		typ := TransactionType("")
+		if tx.Receipt != nil {
+			if tx.Receipt.Bank != "" {
+				bank = tx.Receipt.Bank
+			}
+			typ = tx.Receipt.Type
+		}
+		banks[bank]++
+		types[typ.Label()]++
+	}
+	writeCounts(&result, "Bankalar", banks)
+	writeCounts(&result, "İşlem Türleri", types)
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// writeCounts writes a titled list of counts sorted by name
+func writeCounts(result *strings.Builder, title string, counts map[string]int) {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result.WriteString(fmt.Sprintf("\n**%s**\n", title))
+	for _, name := range names {
+		result.WriteString(fmt.Sprintf("- %s: %d\n", name, counts[name]))
+	}
+}
+
+// formatSupportedBanks lists the banks the registry has parsers for
+func formatSupportedBanks(registry *parserRegistry) string {
+	var result strings.Builder
+	result.WriteString("**Desteklenen bankalar**\n")
+	for _, parser := range registry.parsers {
+		result.WriteString(fmt.Sprintf("- %s\n", parser.Name()))
+	}
+	result.WriteString("\nTanınmayan bankaların dekontları genel kalıplarla işlenir.")
+	return result.String()
+}
+
+// exportUsage explains the parameters of /dekont export
+const exportUsage = "Kullanım: `/dekont export [csv|xlsx] [gün | başlangıç bitiş]`, örneğin `/dekont export xlsx 01.07.2025 31.07.2025`"
+
+// executeExport posts a CSV or XLSX file of the channel's transactions in a
+// period, the last defaultSummaryDays days by default, to the channel
+func (p *Plugin) executeExport(args *model.CommandArgs, params []string) string {
+	format := exportFormatCSV
+	if len(params) > 0 {
+		if f := strings.ToLower(params[0]); f == exportFormatCSV || f == exportFormatXLSX {
+			format, params = f, params[1:]
+		}
+	}
+	from, to, ok := parseExportPeriod(params, time.Now().In(istanbul))
+	if !ok {
+		return exportUsage
+	}
+
+	transactions, err := p.store.List(TransactionQuery{ChannelID: args.ChannelId, From: from, To: to})
+	if err != nil {
+		p.API.LogError("Failed to list transactions for export", "channelId", args.ChannelId, "error", err.Error())
+		return "Dekontlar okunamadı."
+	}
+	if len(transactions) == 0 {
+		return fmt.Sprintf("%s - %s arasında bu kanalda işlenmiş dekont yok.", from.Format("02.01.2006"), to.Format("02.01.2006"))
+	}
+
+	data, err := p.buildExport(transactions, format)
+	if err != nil {
+		p.API.LogError("Failed to build export", "format", format, "error", err.Error())
+		return "Döküm oluşturulamadı."
+	}
+	fileName := exportFileName(format, from, to)
+	fileInfo, appErr := p.API.UploadFile(data, args.ChannelId, fileName)
+	if appErr != nil {
+		p.API.LogError("Failed to upload export", "channelId", args.ChannelId, "error", appErr.Error())
+		return "Döküm kanala eklenemedi."
+	}
+
+	post := &model.Post{
+		ChannelId: args.ChannelId,
+		UserId:    p.botUserID,
+		Message: fmt.Sprintf("**%s - %s dekont dökümü** (%d dekont)",
+			from.Format("02.01.2006"), to.Format("02.01.2006"), len(transactions)),
+		FileIds: []string{fileInfo.Id},
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogError("Failed to post export", "channelId", args.ChannelId, "error", appErr.Error())
+		return "Döküm kanala eklenemedi."
+	}
+	return fmt.Sprintf("`%s` kanala eklendi.", fileName)
+}
+
+// parseExportPeriod reads the export period from a number of days ending
+// today or from a start and an end date, both included. No parameters
+// select the last defaultSummaryDays days.
+func parseExportPeriod(params []string, now time.Time) (from, to time.Time, ok bool) {
+	switch len(params) {
+	case 0, 1:
+		days := defaultSummaryDays
+		if len(params) == 1 {
+			n, err := strconv.Atoi(params[0])
+			if err != nil || n <= 0 {
+				return from, to, false
+			}
+			days = n
+		}
+		return truncateDay(now.AddDate(0, 0, 1-days)), now, true
+	case 2:
+		from, to = parseDate(params[0]), parseDate(params[1])
+		if from.IsZero() || to.IsZero() || to.Before(from) {
+			return from, to, false
+		}
+		return truncateDay(from), truncateDay(to).AddDate(0, 0, 1).Add(-time.Nanosecond), true
+	default:
+		return from, to, false
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+)
+
+func TestParsePostID(t *testing.T) {
+	postID := model.NewId()
+
+	tests := []struct {
+		name     string
+		link     string
+		expected string
+	}{
+		{name: "team permalink", link: "https://chat.example.com/finance/pl/" + postID, expected: postID},
+		{name: "redirect permalink", link: "https://chat.example.com/_redirect/pl/" + postID + "/", expected: postID},
+		{name: "permalink with query", link: "https://chat.example.com/finance/pl/" + postID + "?x=1", expected: postID},
+		{name: "bare post ID", link: postID, expected: postID},
+		{name: "angle brackets", link: "<https://chat.example.com/finance/pl/" + postID + ">", expected: postID},
+		{name: "not a post ID", link: "https://chat.example.com/finance/channels/town-square", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parsePostID(tt.link); result != tt.expected {
+				t.Errorf("parsePostID(%q) = %q, want %q", tt.link, result, tt.expected)
+			}
+		})
+	}
+}
+
+// newCommandTestPlugin returns a plugin with an in-memory store holding two
+// transactions of the finance channel and one of a channel the user cannot
+// read
+func newCommandTestPlugin(t *testing.T) (*Plugin, *plugintest.API) {
+	api, _ := newKVTestAPI()
+	api.On("GetConfig").Return(&model.Config{}).Maybe()
+	p := &Plugin{botUserID: "bot", configuration: &Configuration{BankConfidenceThreshold: 60, HideCredits: true}}
+	p.SetAPI(api)
+	p.store = newTransactionStore(api)
+
+	now := time.Now()
+	for _, tx := range []*Transaction{
+		{ID: "file1_0", PostID: "post1", ChannelID: "finance", Receipt: &Receipt{
+			Bank: "HalkBank", Recipient: "ABC Şirketi", Amount: 10000, AmountText: "100.00", Currency: "TRY", Date: now.AddDate(0, 0, -40)}},
+		{ID: "file2_0", PostID: "post2", ChannelID: "finance", Receipt: &Receipt{
+			Bank: "VakıfBank", Recipient: "XYZ Ltd.", Amount: 25050, AmountText: "250.50", Currency: "TRY", Date: now.AddDate(0, 0, -1),
+			ReferenceNumber: "VB2025000123"}},
+		{ID: "file3_0", PostID: "post3", ChannelID: "board", Receipt: &Receipt{
+			Bank: "Akbank", Recipient: "Gizli A.Ş.", Amount: 99900, AmountText: "999.00", Currency: "TRY", Date: now.AddDate(0, 0, -1),
+			ReferenceNumber: "VB2025000123"}},
+	} {
+		if err := p.store.Save(tx); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	return p, api
+}
+
+func TestExecuteCommand(t *testing.T) {
+	p, api := newCommandTestPlugin(t)
+	api.On("HasPermissionToChannel", "user", "finance", model.PermissionReadChannel).Return(true)
+	api.On("HasPermissionToChannel", "user", "board", model.PermissionReadChannel).Return(false)
+
+	tests := []struct {
+		name     string
+		command  string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "no subcommand shows help",
+			command:  "/dekont",
+			contains: []string{"/dekont parse", "/dekont summary"},
+		},
+		{
+			name:     "unknown subcommand",
+			command:  "/dekont foo",
+			contains: []string{"Bilinmeyen komut: `foo`", "/dekont help"},
+		},
+		{
+			name:     "banks",
+			command:  "/dekont banks",
+			contains: []string{"- VakıfBank", "- HalkBank", "- Ziraat Bankası"},
+			excludes: []string{"Generic"},
+		},
+		{
+			name:     "list newest first",
+			command:  "/dekont list",
+			contains: []string{"**Son 2 dekont** (toplam 2)", "| XYZ Ltd. | 250,50 TL | [Aç](/_redirect/pl/post2) |\n| "},
+		},
+		{
+			name:     "list limited",
+			command:  "/dekont list 1",
+			contains: []string{"**Son 1 dekont** (toplam 2)", "XYZ Ltd."},
+			excludes: []string{"ABC Şirketi"},
+		},
+		{
+			name:     "invalid list count",
+			command:  "/dekont list abc",
+			contains: []string{"Geçersiz adet"},
+		},
+		{
+			name:     "summary of the last 30 days",
+			command:  "/dekont summary",
+			contains: []string{"**Son 30 gün: 1 dekont**", "- 250,50 TL", "- VakıfBank: 1"},
+			excludes: []string{"HalkBank"},
+		},
+		{
+			name:     "summary of the last 60 days",
+			command:  "/dekont summary 60",
+			contains: []string{"**Son 60 gün: 2 dekont**", "- 350,50 TL", "- HalkBank: 1"},
+		},
+		{
+			name:     "find by reference in readable channels",
+			command:  "/dekont find vb 2025000123",
+			contains: []string{"**VB2025000123 referans numaralı dekontlar**", "| VB2025000123 | ", "XYZ Ltd."},
+			excludes: []string{"Gizli A.Ş."},
+		},
+		{
+			name:     "find unknown reference",
+			command:  "/dekont find 42",
+			contains: []string{"`42` referans numaralı dekont bulunamadı."},
+		},
+		{
+			name:     "find without reference",
+			command:  "/dekont find",
+			contains: []string{"Kullanım: `/dekont find <referans no>`"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, appErr := p.ExecuteCommand(nil, &model.CommandArgs{Command: tt.command, ChannelId: "finance", UserId: "user"})
+			if appErr != nil {
+				t.Fatalf("ExecuteCommand() error = %v", appErr)
+			}
+			if response.ResponseType != model.CommandResponseTypeEphemeral {
+				t.Errorf("ExecuteCommand() response type = %q, want ephemeral", response.ResponseType)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(response.Text, expected) {
+					t.Errorf("ExecuteCommand(%q) = %q, want it to contain %q", tt.command, response.Text, expected)
+				}
+			}
+			for _, unexpected := range tt.excludes {
+				if strings.Contains(response.Text, unexpected) {
+					t.Errorf("ExecuteCommand(%q) = %q, want it not to contain %q", tt.command, response.Text, unexpected)
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteCommandParse(t *testing.T) {
+	postID := model.NewId()
+	pdfData := buildTestPDF([]string{"HALKBANK", "ALICI : Test User", "ISLEM TUTARI (TL) : 100.00"})
+
+	p, api := newCommandTestPlugin(t)
+	api.On("GetPost", postID).Return(&model.Post{Id: postID, ChannelId: "private", UserId: "author", FileIds: []string{"file"}}, nil)
+	api.On("HasPermissionToChannel", "member", "private", model.PermissionReadChannel).Return(true)
+	api.On("HasPermissionToChannel", "outsider", "private", model.PermissionReadChannel).Return(false)
+	api.On("GetFileInfo", "file").Return(&model.FileInfo{Id: "file", Name: "dekont.pdf", Size: int64(len(pdfData))}, nil)
+	api.On("GetFile", "file").Return(pdfData, nil)
+	p.configuration.MaxFileSizeMB = 10
+	p.configuration.MaxPages = 20
+
+	command := "/dekont parse https://chat.example.com/finance/pl/" + postID
+
+	response, _ := p.ExecuteCommand(nil, &model.CommandArgs{Command: command, UserId: "member"})
+	if !strings.Contains(response.Text, "**Banka**: HalkBank") || !strings.Contains(response.Text, "**Alıcı**: Test User") {
+		t.Errorf("ExecuteCommand(parse) = %q, want the parsed receipt", response.Text)
+	}
+
+	response, _ = p.ExecuteCommand(nil, &model.CommandArgs{Command: command, UserId: "outsider"})
+	if strings.Contains(response.Text, "Test User") || !strings.Contains(response.Text, "yetkiniz yok") {
+		t.Errorf("ExecuteCommand(parse) by non-member = %q, want permission error", response.Text)
+	}
+
+	api.On("HasPermissionToChannel", "member", "private", model.PermissionEditOthersPosts).Return(false)
+	response, _ = p.ExecuteCommand(nil, &model.CommandArgs{Command: "/dekont reprocess " + postID, UserId: "member"})
+	if !strings.Contains(response.Text, "yeniden işleme yetkiniz yok") {
+		t.Errorf("ExecuteCommand(reprocess) by non-author = %q, want permission error", response.Text)
+	}
+}
+
+func TestExecuteCommandReprocessAppend(t *testing.T) {
+	postID := model.NewId()
+	pdfData := buildTestPDF([]string{"HALKBANK", "ALICI : Test User", "ISLEM TUTARI (TL) : 100.00"})
+	receiver := newWebhookReceiver(t)
+
+	p, api := newCommandTestPlugin(t)
+	post := &model.Post{Id: postID, ChannelId: "finance", UserId: "author", Message: "Kira dekontu", FileIds: []string{"file"}}
+	api.On("GetPost", postID).Return(post, nil)
+	api.On("HasPermissionToChannel", "author", "finance", model.PermissionReadChannel).Return(true)
+	api.On("GetFileInfo", "file").Return(&model.FileInfo{Id: "file", Name: "dekont.pdf", Size: int64(len(pdfData))}, nil)
+	api.On("GetFile", "file").Return(pdfData, nil)
+	api.On("UpdatePost", post).Return(post, nil)
+	p.configuration.MaxFileSizeMB = 10
+	p.configuration.MaxPages = 20
+	p.configuration.OutputMode = outputModeAppend
+	p.configuration.WebhookSecret = "s3cret"
+	p.configuration.webhookURLs = []string{receiver.URL}
+
+	var rendered string
+	for i := 0; i < 2; i++ {
+		response, _ := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/dekont reprocess " + postID, UserId: "author"})
+		if response.Text != "Gönderi yeniden işlendi." {
+			t.Fatalf("ExecuteCommand(reprocess) = %q", response.Text)
+		}
+		if i == 0 {
+			rendered = post.Message
+		}
+	}
+
+	if !strings.HasPrefix(post.Message, "Kira dekontu\n\n") || strings.Count(post.Message, "**Alıcı**: Test User") != 1 {
+		t.Errorf("post.Message after reprocessing twice = %q, want the original message with one rendering", post.Message)
+	}
+	if post.Message != rendered {
+		t.Errorf("post.Message = %q, want the first rendering %q", post.Message, rendered)
+	}
+
+	deliveries, err := p.store.ListDeliveries()
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Errorf("ListDeliveries() returned %d deliveries after reprocessing twice, want 1", len(deliveries))
+	}
+}
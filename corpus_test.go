@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The receipt corpus holds anonymized text dumps of real receipt layouts in
+// testdata/corpus/<bank>/<case>.txt, with pages separated by form feed
+// lines, next to <case>.json holding the receipts the dump must parse to.
+// Every dump is parsed as text and again from a synthetic PDF carrying the
+// same lines. Run
+//
+//	go test -run TestReceiptCorpus -update
+//
+// to rewrite the golden files after an intended parser change, and review
+// the diff before committing it.
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the receipt corpus")
+
+const corpusDir = "testdata/corpus"
+
+// corpusCase is a text dump of the corpus and its golden file
+type corpusCase struct {
+	bank   string
+	name   string
+	text   string
+	golden string
+}
+
+// loadCorpus returns the cases of the corpus sorted by bank and name
+func loadCorpus(t *testing.T) []corpusCase {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(corpusDir, "*", "*.txt"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no corpus cases found in %s: %v", corpusDir, err)
+	}
+	sort.Strings(paths)
+
+	cases := make([]corpusCase, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("os.ReadFile(%s) error = %v", path, err)
+		}
+		cases = append(cases, corpusCase{
+			bank:   filepath.Base(filepath.Dir(path)),
+			name:   strings.TrimSuffix(filepath.Base(path), ".txt"),
+			text:   string(data),
+			golden: strings.TrimSuffix(path, ".txt") + ".json",
+		})
+	}
+	return cases
+}
+
+// pages splits the dump on its form feed lines
+func (cc corpusCase) pages() []string {
+	return strings.Split(cc.text, "\f\n")
+}
+
+// pdfPages renders the dump as a synthetic PDF and extracts its pages again
+func (cc corpusCase) pdfPages(t *testing.T) []string {
+	t.Helper()
+	var lines [][]string
+	for _, page := range cc.pages() {
+		lines = append(lines, strings.Split(strings.TrimRight(page, "\n"), "\n"))
+	}
+	pages, err := extractPages(openTestPDF(t, buildTestPDF(lines...)), 0)
+	if err != nil {
+		t.Fatalf("extractPages() error = %v", err)
+	}
+	return pages
+}
+
+// receiptFields decodes receipts into their JSON fields, the form the golden
+// files are compared in
+func receiptFields(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+	var fields []map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	return fields
+}
+
+// fieldAccuracy counts the receipts whose field matched the golden value
+type fieldAccuracy struct {
+	matched int
+	total   int
+}
+
+// corpusReport holds the accuracy of every field, by bank and source
+// ("text" or "pdf")
+type corpusReport map[string]map[string]*fieldAccuracy
+
+// compare scores got against want and returns the mismatches
+func (cr corpusReport) compare(group string, want, got []map[string]interface{}) []string {
+	if cr[group] == nil {
+		cr[group] = map[string]*fieldAccuracy{}
+	}
+
+	var mismatches []string
+	if len(got) != len(want) {
+		mismatches = append(mismatches, fmt.Sprintf("parsed %d receipts, want %d", len(got), len(want)))
+	}
+	for i, wantFields := range want {
+		gotFields := map[string]interface{}{}
+		if i < len(got) {
+			gotFields = got[i]
+		}
+
+		names := map[string]bool{}
+		for name := range wantFields {
+			names[name] = true
+		}
+		for name := range gotFields {
+			names[name] = true
+		}
+		for name := range names {
+			accuracy := cr[group][name]
+			if accuracy == nil {
+				accuracy = &fieldAccuracy{}
+				cr[group][name] = accuracy
+			}
+			accuracy.total++
+			if reflect.DeepEqual(wantFields[name], gotFields[name]) {
+				accuracy.matched++
+				continue
+			}
+			mismatches = append(mismatches, fmt.Sprintf("receipt %d %s = %v, want %v", i+1, name, gotFields[name], wantFields[name]))
+		}
+	}
+	sort.Strings(mismatches)
+	return mismatches
+}
+
+// String formats the report as one line per bank and field
+func (cr corpusReport) String() string {
+	groups := make([]string, 0, len(cr))
+	for group := range cr {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var b strings.Builder
+	for _, group := range groups {
+		names := make([]string, 0, len(cr[group]))
+		for name := range cr[group] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			accuracy := cr[group][name]
+			fmt.Fprintf(&b, "%-22s %-18s %3d/%-3d %6.1f%%\n", group, name, accuracy.matched, accuracy.total,
+				100*float64(accuracy.matched)/float64(accuracy.total))
+		}
+	}
+	return b.String()
+}
+
+func TestReceiptCorpus(t *testing.T) {
+	report := corpusReport{}
+
+	for _, cc := range loadCorpus(t) {
+		t.Run(cc.bank+"/"+cc.name, func(t *testing.T) {
+			receipts := defaultRegistry.extractReceipts(cc.pages())
+			data, err := json.MarshalIndent(receipts, "", "  ")
+			if err != nil {
+				t.Fatalf("json.MarshalIndent() error = %v", err)
+			}
+			if *updateGolden {
+				if err := os.WriteFile(cc.golden, append(data, '\n'), 0o644); err != nil {
+					t.Fatalf("os.WriteFile(%s) error = %v", cc.golden, err)
+				}
+			}
+
+			golden, err := os.ReadFile(cc.golden)
+			if err != nil {
+				t.Fatalf("os.ReadFile(%s) error = %v, run with -update to create it", cc.golden, err)
+			}
+			want := receiptFields(t, golden)
+
+			for _, mismatch := range report.compare(cc.bank+" (text)", want, receiptFields(t, data)) {
+				t.Errorf("text: %s", mismatch)
+			}
+
+			pdfData, err := json.Marshal(defaultRegistry.extractReceipts(cc.pdfPages(t)))
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			for _, mismatch := range report.compare(cc.bank+" (pdf)", want, receiptFields(t, pdfData)) {
+				t.Errorf("pdf: %s", mismatch)
+			}
+		})
+	}
+
+	t.Logf("field accuracy by bank:\n%s", report)
+}
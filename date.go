@@ -0,0 +1,108 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// Embed the zone database so Istanbul time does not depend on the server
+	_ "time/tzdata"
+	"unicode"
+)
+
+// istanbul is the time zone receipt dates are printed in
+var istanbul = loadIstanbul()
+
+// loadIstanbul loads Europe/Istanbul, falling back to its fixed offset when
+// the server has no time zone database. Turkey has stayed on UTC+3 all year
+// since 2016.
+func loadIstanbul() *time.Location {
+	if location, err := time.LoadLocation("Europe/Istanbul"); err == nil {
+		return location
+	}
+	return time.FixedZone("+03", 3*60*60)
+}
+
+// Date regex fragments. Each date pattern captures day, month and year, the
+// optional time pattern captures hour, minute and second.
+const (
+	timePattern        = `(?:\s*[-,/]?\s*(?:saat\s*:?\s*)?([0-2]?[0-9]):([0-5][0-9])(?::([0-5][0-9]))?)?`
+	numericDatePattern = `\b([0-3]?[0-9])[./-]([01]?[0-9])[./-]((?:19|20)[0-9]{2})\b`
+	monthDatePattern   = `\b([0-3]?[0-9])\s+(ocak|[şs]ubat|mart|n[iİIı]san|may[ıIiİ]s|haz[iİIı]ran|temmuz|a[ğg]ustos|` +
+		`eyl[üu]l|ek[iİIı]m|kas[ıIiİ]m|aral[ıIiİ]k)\s+((?:19|20)[0-9]{2})\b`
+
+	// anyDatePattern captures a date in either form with its time
+	anyDatePattern = `((?:` + numericDatePattern + `|` + monthDatePattern + `)` + timePattern + `)`
+)
+
+var (
+	reNumericDate = regexp.MustCompile(`(?i)` + numericDatePattern + timePattern)
+	reMonthDate   = regexp.MustCompile(`(?i)` + monthDatePattern + timePattern)
+
+	// asciiFolder maps Turkish letters onto ASCII for month name lookups
+	asciiFolder = strings.NewReplacer("ı", "i", "ş", "s", "ğ", "g", "ü", "u", "ö", "o", "ç", "c")
+)
+
+// turkishMonths maps the ASCII folded Turkish month names to months
+var turkishMonths = map[string]time.Month{
+	"ocak": time.January, "subat": time.February, "mart": time.March,
+	"nisan": time.April, "mayis": time.May, "haziran": time.June,
+	"temmuz": time.July, "agustos": time.August, "eylul": time.September,
+	"ekim": time.October, "kasim": time.November, "aralik": time.December,
+}
+
+// parseDate finds the first date in text, such as "30.07.2025",
+// "30/07/2025 14:30" or "17 Ekim 2026 09:15:00", and returns it in the
+// Europe/Istanbul time zone. It returns the zero time when the text contains
+// no valid date.
+func parseDate(text string) time.Time {
+	var first []string
+	firstIndex := -1
+	monthNames := false
+	for _, re := range []*regexp.Regexp{reNumericDate, reMonthDate} {
+		loc := re.FindStringSubmatchIndex(text)
+		if loc == nil || (firstIndex >= 0 && loc[0] >= firstIndex) {
+			continue
+		}
+		first, firstIndex = submatches(text, loc), loc[0]
+		monthNames = re == reMonthDate
+	}
+	if first == nil {
+		return time.Time{}
+	}
+
+	day, _ := strconv.Atoi(first[1])
+	year, _ := strconv.Atoi(first[3])
+	var month time.Month
+	if monthNames {
+		month = turkishMonths[asciiFolder.Replace(strings.ToLowerSpecial(unicode.TurkishCase, first[2]))]
+	} else {
+		number, _ := strconv.Atoi(first[2])
+		month = time.Month(number)
+	}
+
+	var clock [3]int
+	for i, value := range first[4:7] {
+		if value != "" {
+			clock[i], _ = strconv.Atoi(value)
+		}
+	}
+
+	date := time.Date(year, month, day, clock[0], clock[1], clock[2], 0, istanbul)
+	// time.Date normalizes overflowing values, so 31.02 would become March
+	if date.Day() != day || date.Month() != month || date.Hour() != clock[0] {
+		return time.Time{}
+	}
+	return date
+}
+
+// submatches returns the submatch strings of a FindStringSubmatchIndex result
+func submatches(text string, loc []int) []string {
+	result := make([]string, len(loc)/2)
+	for i := range result {
+		if loc[2*i] >= 0 {
+			result[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+	}{
+		{
+			name:     "date only",
+			input:    "15.07.2025",
+			expected: time.Date(2025, 7, 15, 0, 0, 0, 0, istanbul),
+		},
+		{
+			name:     "date with seconds",
+			input:    "30.07.2025 14:30:25",
+			expected: time.Date(2025, 7, 30, 14, 30, 25, 0, istanbul),
+		},
+		{
+			name:     "slashes with minutes",
+			input:    "05/01/2026 09:05",
+			expected: time.Date(2026, 1, 5, 9, 5, 0, 0, istanbul),
+		},
+		{
+			name:     "single digit day and month",
+			input:    "5.1.2026",
+			expected: time.Date(2026, 1, 5, 0, 0, 0, 0, istanbul),
+		},
+		{
+			name:     "time after a dash",
+			input:    "30.07.2025 - 14:30",
+			expected: time.Date(2025, 7, 30, 14, 30, 0, 0, istanbul),
+		},
+		{
+			name:     "Turkish month name",
+			input:    "17 Ekim 2026",
+			expected: time.Date(2026, 10, 17, 0, 0, 0, 0, istanbul),
+		},
+		{
+			name:     "upper case Turkish month name with time",
+			input:    "1 AĞUSTOS 2025 Saat: 08:15:30",
+			expected: time.Date(2025, 8, 1, 8, 15, 30, 0, istanbul),
+		},
+		{
+			name:     "dotted capital I in month name",
+			input:    "3 NİSAN 2025",
+			expected: time.Date(2025, 4, 3, 0, 0, 0, 0, istanbul),
+		},
+		{
+			name:     "month name without Turkish letters",
+			input:    "12 Subat 2025",
+			expected: time.Date(2025, 2, 12, 0, 0, 0, 0, istanbul),
+		},
+		{
+			name:     "date inside text",
+			input:    "İşlem 30.07.2025 tarihinde gerçekleşti",
+			expected: time.Date(2025, 7, 30, 0, 0, 0, 0, istanbul),
+		},
+		{
+			name:     "invalid day",
+			input:    "31.02.2025",
+			expected: time.Time{},
+		},
+		{
+			name:     "invalid hour",
+			input:    "15.07.2025 25:00",
+			expected: time.Time{},
+		},
+		{
+			name:     "unknown layout",
+			input:    "yesterday",
+			expected: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseDate(tt.input)
+			if !result.Equal(tt.expected) {
+				t.Errorf("parseDate() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDateIsInIstanbulTime(t *testing.T) {
+	result := parseDate("15.07.2025 14:30")
+	if !result.Equal(time.Date(2025, 7, 15, 11, 30, 0, 0, time.UTC)) {
+		t.Errorf("parseDate() = %v, want 11:30 UTC", result.UTC())
+	}
+}
+
+func TestExtractDateForEveryBank(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Yapı Kredi", input: "YAPI KREDİ\nALICI ADI: Test User\nGİDEN EFT TUTARI: 100,00\nİŞLEM TARİHİ: 17.10.2026 10:15"},
+		{name: "Kuveyt Türk", input: "KUVEYT TÜRK\nAlıcı: Test User\nTutar: 100,00 TL\nTarih: 17/10/2026 10:15"},
+		{name: "Garanti", input: "GARANTİ BBVA\nALICI: Test User\nTUTAR: 100,00 TL\n17 Ekim 2026 10:15"},
+	}
+
+	expected := time.Date(2026, 10, 17, 10, 15, 0, 0, istanbul)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := extractReceipt(tt.input)
+			if receipt == nil || !receipt.Date.Equal(expected) {
+				t.Errorf("extractReceipt() date = %v, want %v", receipt, expected)
+			}
+		})
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		receipt  *Receipt
+		expected string
+	}{
+		{
+			name:     "date only",
+			receipt:  &Receipt{Date: time.Date(2026, 10, 17, 0, 0, 0, 0, istanbul), DateText: "17 Ekim 2026"},
+			expected: "17.10.2026",
+		},
+		{
+			name:     "minutes",
+			receipt:  &Receipt{Date: time.Date(2026, 10, 17, 9, 5, 0, 0, istanbul)},
+			expected: "17.10.2026 09:05",
+		},
+		{
+			name:     "seconds converted to Istanbul time",
+			receipt:  &Receipt{Date: time.Date(2026, 10, 17, 6, 5, 30, 0, time.UTC)},
+			expected: "17.10.2026 09:05:30",
+		},
+		{
+			name:     "unparsed date shown as printed",
+			receipt:  &Receipt{DateText: "Dün"},
+			expected: "Dün",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := formatDate(tt.receipt); result != tt.expected {
+				t.Errorf("formatDate() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
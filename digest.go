@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Digest schedules
+const (
+	digestOff     = "off"
+	digestDaily   = "daily"
+	digestWeekly  = "weekly"
+	digestMonthly = "monthly"
+)
+
+const (
+	// defaultDigestHour is the hour of day, in Istanbul time, digests are
+	// posted at when DigestHour is out of range
+	defaultDigestHour = 9
+	// digestCheckInterval is how often the job looks for a digest to post
+	digestCheckInterval = 5 * time.Minute
+
+	// digestLockKey is held by the server posting the digests, so that only
+	// one server of a cluster posts them. Its TTL is well above the time a
+	// run across many channels takes, so the lock does not expire mid-run.
+	digestLockKey = "digest_lock"
+	digestLockTTL = 30 * 60
+	// digestSentPrefix marks the periods a channel received its digest for
+	digestSentPrefix = "digest_sent_"
+	digestSentTTL    = 62 * 24 * 60 * 60
+
+	// maxDigestCounterparties bounds the counterparties listed in a digest
+	maxDigestCounterparties = 10
+)
+
+// digestGroup is the number and total of the receipts of a bank or a
+// counterparty
+type digestGroup struct {
+	name   string
+	count  int
+	totals map[string]Amount
+}
+
+// postDueDigests posts the digest of the last completed period to every
+// digest channel that has not received it yet. The period is completed once
+// the digest hour of the day after it has passed.
+func (p *Plugin) postDueDigests(now time.Time) {
+	config := p.getConfiguration()
+	channels := splitChannelIDs(config.DigestChannels)
+	if config.DigestSchedule == "" || config.DigestSchedule == digestOff || len(channels) == 0 {
+		return
+	}
+	from, to, ok := digestPeriod(config.DigestSchedule, now.In(istanbul), config.DigestHour)
+	if !ok {
+		return
+	}
+
+	locked, unlock := p.tryLock(digestLockKey, digestLockTTL)
+	if !locked {
+		return
+	}
+	defer unlock()
+
+	for _, channelID := range channels {
+		sentKey := fmt.Sprintf("%s%s_%s_%s", digestSentPrefix, channelID, config.DigestSchedule, from.Format(dayIndexLayout))
+		sent, appErr := p.API.KVGet(sentKey)
+		if appErr != nil {
+			p.API.LogError("Failed to read digest state", "channelId", channelID, "error", appErr.Error())
+			continue
+		}
+		if sent != nil {
+			continue
+		}
+
+		if err := p.postDigest(channelID, config.DigestSchedule, from, to, config); err != nil {
+			p.API.LogError("Failed to post digest", "channelId", channelID, "error", err.Error())
+			continue
+		}
+		if _, appErr := p.API.KVSetWithOptions(sentKey, []byte("1"), model.PluginKVSetOptions{ExpireInSeconds: digestSentTTL}); appErr != nil {
+			p.API.LogError("Failed to save digest state", "channelId", channelID, "error", appErr.Error())
+		}
+	}
+}
+
+// postDigest posts the digest of the receipts processed in the channel
+// between from and to. Nothing is posted for a period without receipts or
+// failed parses.
+func (p *Plugin) postDigest(channelID, schedule string, from, to time.Time, config *Configuration) error {
+	channelTransactions, err := p.store.List(TransactionQuery{ChannelID: channelID})
+	if err != nil {
+		return err
+	}
+	var transactions []*Transaction
+	for _, tx := range channelTransactions {
+		if created := time.UnixMilli(tx.CreateAt); !created.Before(from) && created.Before(to) {
+			transactions = append(transactions, tx)
+		}
+	}
+	failures, err := p.store.ListFailures(channelID, from, to.Add(-time.Millisecond))
+	if err != nil {
+		return err
+	}
+	if len(transactions) == 0 && len(failures) == 0 {
+		return nil
+	}
+
+	post := &model.Post{
+		ChannelId: channelID,
+		UserId:    p.botUserID,
+		Message:   p.formatDigest(schedule, from, to, transactions, failures, config),
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// digestPeriod returns the last period of the schedule, [from, to), whose
+// digest is due at now
+func digestPeriod(schedule string, now time.Time, hour int) (from, to time.Time, ok bool) {
+	var previous func(time.Time) time.Time
+	to = truncateDay(now)
+	switch schedule {
+	case digestDaily:
+		previous = func(t time.Time) time.Time { return t.AddDate(0, 0, -1) }
+	case digestWeekly:
+		to = to.AddDate(0, 0, -(int(to.Weekday())+6)%7)
+		previous = func(t time.Time) time.Time { return t.AddDate(0, 0, -7) }
+	case digestMonthly:
+		to = to.AddDate(0, 0, 1-to.Day())
+		previous = func(t time.Time) time.Time { return t.AddDate(0, -1, 0) }
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+
+	if now.Before(to.Add(time.Duration(hour) * time.Hour)) {
+		to = previous(to)
+	}
+	return previous(to), to, true
+}
+
+// formatDigest renders the receipts processed in a period with their totals
+// per currency, bank and counterparty, followed by the failed parses and the
+// receipts that need review
+func (p *Plugin) formatDigest(schedule string, from, to time.Time, transactions []*Transaction, failures []*ParseFailure, config *Configuration) string {
+	var result strings.Builder
+
+	titles := map[string]string{digestDaily: "Günlük", digestWeekly: "Haftalık", digestMonthly: "Aylık"}
+	period := from.Format("02.01.2006")
+	if last := to.AddDate(0, 0, -1); !last.Equal(from) {
+		period += " - " + last.Format("02.01.2006")
+	}
+	result.WriteString(fmt.Sprintf("**%s dekont özeti** (%s): %d dekont\n", titles[schedule], period, len(transactions)))
+
+	totals := totalsByCurrency(transactions)
+	if len(totals) > 0 {
+		result.WriteString(fmt.Sprintf("\n**Toplam Tutar**: %s\n", formatTotals(totals, config)))
+	}
+
+	banks := map[string]*digestGroup{}
+	parties := map[string]*digestGroup{}
+	var review []*Transaction
+	for _, tx := range transactions {
+		receipt := tx.Receipt
+		if receipt == nil {
+			receipt = &Receipt{}
+		}
+		bank := receipt.Bank
+		if bank == "" {
+			bank = "Tespit edilemedi"
+		}
+		addToDigestGroup(banks, bank, bank, receipt)
+
+		party := receipt.Recipient
+		if party == "" {
+			party = receipt.Sender
+		}
+		if party != "" {
+			addToDigestGroup(parties, normalizeCounterparty(party), party, receipt)
+		}
+
+		if needsReview(receipt, config.BankConfidenceThreshold) || receipt.TotalMismatch {
+			review = append(review, tx)
+		}
+	}
+	writeDigestGroups(&result, "Banka", banks, 0, config)
+	writeDigestGroups(&result, "Karşı Taraf", parties, maxDigestCounterparties, config)
+
+	if len(failures) > 0 || len(review) > 0 {
+		result.WriteString("\n**İnceleme Gerekenler**\n")
+		for _, failure := range failures {
+			result.WriteString(fmt.Sprintf("- [%s](%s): %s\n", failure.FileName, p.permalink(failure.PostID), failure.Reason))
+		}
+		for _, tx := range review {
+			result.WriteString(fmt.Sprintf("- [%s](%s): %s\n", tx.FileName, p.permalink(tx.PostID), reviewReason(tx.Receipt, config)))
+		}
+	}
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// reviewReason explains why a receipt needs review
+func reviewReason(receipt *Receipt, config *Configuration) string {
+	if receipt == nil || needsReview(receipt, config.BankConfidenceThreshold) {
+		return "banka tespiti belirsiz"
+	}
+	return "toplam tutar tutarsız"
+}
+
+// addToDigestGroup counts the receipt in the group under key, named after
+// the first receipt added to it
+func addToDigestGroup(groups map[string]*digestGroup, key, name string, receipt *Receipt) {
+	group, ok := groups[key]
+	if !ok {
+		group = &digestGroup{name: name, totals: map[string]Amount{}}
+		groups[key] = group
+	}
+	group.count++
+	if receipt.Currency != "" {
+		group.totals[receipt.Currency] += receipt.Amount
+	}
+}
+
+// writeDigestGroups writes the groups as a table, the groups with most
+// receipts first. A positive limit bounds the rows of the table.
+func writeDigestGroups(result *strings.Builder, title string, groups map[string]*digestGroup, limit int, config *Configuration) {
+	if len(groups) == 0 {
+		return
+	}
+	sorted := make([]*digestGroup, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	result.WriteString(fmt.Sprintf("\n| %s | Dekont | Toplam |\n|---|---:|---:|\n", title))
+	for _, group := range sorted {
+		result.WriteString(fmt.Sprintf("| %s | %d | %s |\n", escapeTableCell(group.name), group.count, formatTotals(group.totals, config)))
+	}
+}
+
+// splitChannelIDs returns the channel IDs of a comma-separated setting
+func splitChannelIDs(value string) []string {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
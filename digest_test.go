@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDigestPeriod(t *testing.T) {
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, time.July, day, hour, 0, 0, 0, istanbul)
+	}
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, istanbul)
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		now      time.Time
+		from, to time.Time
+		ok       bool
+	}{
+		{name: "daily after hour", schedule: digestDaily, now: at(2, 10), from: date(2025, time.July, 1), to: date(2025, time.July, 2), ok: true},
+		{name: "daily before hour", schedule: digestDaily, now: at(2, 8), from: date(2025, time.June, 30), to: date(2025, time.July, 1), ok: true},
+		{name: "weekly midweek", schedule: digestWeekly, now: at(2, 10), from: date(2025, time.June, 23), to: date(2025, time.June, 30), ok: true},
+		{name: "weekly monday before hour", schedule: digestWeekly, now: at(7, 8), from: date(2025, time.June, 23), to: date(2025, time.June, 30), ok: true},
+		{name: "weekly sunday", schedule: digestWeekly, now: at(6, 10), from: date(2025, time.June, 23), to: date(2025, time.June, 30), ok: true},
+		{name: "monthly", schedule: digestMonthly, now: at(15, 10), from: date(2025, time.June, 1), to: date(2025, time.July, 1), ok: true},
+		{name: "monthly first day before hour", schedule: digestMonthly, now: at(1, 8), from: date(2025, time.May, 1), to: date(2025, time.June, 1), ok: true},
+		{name: "off", schedule: digestOff, now: at(2, 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, ok := digestPeriod(tt.schedule, tt.now, 9)
+			if ok != tt.ok || !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("digestPeriod(%q, %v) = %v, %v, %v, want %v, %v, %v", tt.schedule, tt.now, from, to, ok, tt.from, tt.to, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPostDueDigests(t *testing.T) {
+	api, kv := newKVTestAPI()
+	api.On("GetConfig").Return(&model.Config{}).Maybe()
+	p := &Plugin{botUserID: "bot", configuration: &Configuration{
+		BankConfidenceThreshold: 60,
+		DigestSchedule:          digestDaily,
+		DigestChannels:          "finance, board",
+		DigestHour:              9,
+	}}
+	p.SetAPI(api)
+	p.store = newTransactionStore(api)
+
+	processed := time.Date(2025, time.July, 1, 14, 0, 0, 0, istanbul).UnixMilli()
+	for _, tx := range []*Transaction{
+		{ID: "file1_0", FileName: "halk.pdf", PostID: "post1", ChannelID: "finance", CreateAt: processed, Receipt: &Receipt{
+			Bank: "HalkBank", BankConfidence: 0.9, Recipient: "ABC Şirketi", Amount: 10000, Currency: "TRY"}},
+		{ID: "file2_0", FileName: "vakif.pdf", PostID: "post2", ChannelID: "finance", CreateAt: processed, Receipt: &Receipt{
+			Bank: "VakıfBank", BankConfidence: 0.4, Recipient: "abc şirketi", Amount: 25050, Currency: "TRY"}},
+		{ID: "file3_0", FileName: "usd.pdf", PostID: "post3", ChannelID: "finance", CreateAt: processed, Receipt: &Receipt{
+			Bank: "HalkBank", BankConfidence: 0.9, Recipient: "XYZ Ltd.", Amount: 5000, Currency: "USD"}},
+		{ID: "file4_0", FileName: "old.pdf", PostID: "post4", ChannelID: "finance",
+			CreateAt: time.Date(2025, time.June, 30, 14, 0, 0, 0, istanbul).UnixMilli(), Receipt: &Receipt{
+				Bank: "Akbank", BankConfidence: 0.9, Recipient: "Eski Ltd.", Amount: 70000, Currency: "TRY"}},
+	} {
+		if err := p.store.Save(tx); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if err := p.store.SaveFailure(&ParseFailure{FileID: "file5", FileName: "bozuk.pdf", PostID: "post5", ChannelID: "finance",
+		CreateAt: processed, Reason: "dekont bulunamadı"}); err != nil {
+		t.Fatalf("SaveFailure() error = %v", err)
+	}
+
+	var posts []*model.Post
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(
+		func(post *model.Post) *model.Post {
+			posts = append(posts, post)
+			return post
+		},
+		func(*model.Post) *model.AppError { return nil },
+	)
+
+	now := time.Date(2025, time.July, 2, 10, 0, 0, 0, istanbul)
+
+	// The lock held by another server keeps this one from posting
+	kv.set(digestLockKey, []byte("other"))
+	p.postDueDigests(now)
+	if len(posts) != 0 {
+		t.Fatalf("postDueDigests() with held lock posted %d digests, want 0", len(posts))
+	}
+	kv.set(digestLockKey, nil)
+
+	p.postDueDigests(now)
+	if len(posts) != 1 {
+		t.Fatalf("postDueDigests() posted %d digests, want 1", len(posts))
+	}
+	post := posts[0]
+	if post.ChannelId != "finance" || post.UserId != "bot" {
+		t.Errorf("digest posted to %q as %q, want finance as bot", post.ChannelId, post.UserId)
+	}
+	for _, want := range []string{
+		"**Günlük dekont özeti** (01.07.2025): 3 dekont",
+		"**Toplam Tutar**: 350,50 TL + 50,00 USD",
+		"| HalkBank | 2 | 100,00 TL + 50,00 USD |",
+		"| VakıfBank | 1 | 250,50 TL |",
+		"| ABC Şirketi | 2 | 350,50 TL |",
+		"| XYZ Ltd. | 1 | 50,00 USD |",
+		"- [bozuk.pdf](/_redirect/pl/post5): dekont bulunamadı",
+		"- [vakif.pdf](/_redirect/pl/post2): banka tespiti belirsiz",
+	} {
+		if !strings.Contains(post.Message, want) {
+			t.Errorf("digest = %q, want it to contain %q", post.Message, want)
+		}
+	}
+	if strings.Contains(post.Message, "Akbank") {
+		t.Errorf("digest = %q, want receipts of other periods left out", post.Message)
+	}
+	if kv.get(digestLockKey) != nil {
+		t.Error("postDueDigests() did not release the lock")
+	}
+
+	// The digest of a period is posted once
+	p.postDueDigests(now.Add(digestCheckInterval))
+	if len(posts) != 1 {
+		t.Errorf("postDueDigests() posted %d digests after the first run, want 1", len(posts))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Fingerprint key prefixes. A receipt is a duplicate when the same file was
+// posted before or when another file carried the same parsed receipt.
+const (
+	fileFingerprintPrefix    = "dup_file_"
+	receiptFingerprintPrefix = "dup_receipt_"
+)
+
+// fingerprintRecord points to the post that first processed a fingerprint
+type fingerprintRecord struct {
+	PostID    string `json:"post_id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// fileFingerprintKey returns the fingerprint key of the file contents
+func fileFingerprintKey(data []byte) string {
+	return fileFingerprintPrefix + hashHex(string(data))
+}
+
+// receiptFingerprintKey returns the fingerprint key of the parsed receipt. The
+// bank's reference number identifies a receipt on its own; without one the
+// amount, date and parties are used. It returns an empty string when the
+// receipt lacks the amount or date to identify it reliably.
+func receiptFingerprintKey(receipt *Receipt) string {
+	if receipt.ReferenceNumber != "" {
+		return receiptFingerprintPrefix + hashHex(strings.Join([]string{"ref", receipt.Bank, receipt.ReferenceNumber}, "|"))
+	}
+
+	date := receipt.DateText
+	if !receipt.Date.IsZero() {
+		date = receipt.Date.Format("2006-01-02T15:04:05")
+	}
+	if receipt.Amount == 0 || date == "" {
+		return ""
+	}
+
+	return receiptFingerprintPrefix + hashHex(strings.Join([]string{
+		receipt.Amount.String(),
+		receipt.Currency,
+		date,
+		normalizeCounterparty(receipt.Sender),
+		normalizeCounterparty(receipt.Recipient),
+	}, "|"))
+}
+
+// hashHex returns the first 128 bits of the SHA-256 of value in hex
+func hashHex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
+
+// claimFingerprint atomically records post as the first to process key. It
+// returns the record of the original post when another post claimed the key
+// before.
+func (p *Plugin) claimFingerprint(key string, post *model.Post) (*fingerprintRecord, error) {
+	data, err := json.Marshal(&fingerprintRecord{PostID: post.Id, ChannelID: post.ChannelId})
+	if err != nil {
+		return nil, err
+	}
+
+	saved, appErr := p.API.KVCompareAndSet(key, nil, data)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if saved {
+		return nil, nil
+	}
+
+	existing, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	var original fingerprintRecord
+	if err := json.Unmarshal(existing, &original); err != nil {
+		return nil, err
+	}
+	if original.PostID == post.Id {
+		// The same post is being processed again
+		return nil, nil
+	}
+	return &original, nil
+}
+
+// claimReceipts claims the fingerprints of the file and of every receipt for
+// post. It returns the receipts not processed before, the original posts of
+// the duplicates and the claimed keys, which must be released if publishing
+// the receipts fails.
+func (p *Plugin) claimReceipts(post *model.Post, data []byte, receipts []*Receipt) (
+	fresh []*Receipt, duplicates []*fingerprintRecord, claimed []string, err error) {
+	fileKey := fileFingerprintKey(data)
+	original, err := p.claimFingerprint(fileKey, post)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if original != nil {
+		return nil, []*fingerprintRecord{original}, nil, nil
+	}
+	claimed = append(claimed, fileKey)
+
+	for _, receipt := range receipts {
+		key := receiptFingerprintKey(receipt)
+		if key == "" {
+			fresh = append(fresh, receipt)
+			continue
+		}
+
+		original, err = p.claimFingerprint(key, post)
+		if err != nil {
+			p.releaseFingerprints(claimed)
+			return nil, nil, nil, err
+		}
+		if original != nil {
+			duplicates = append(duplicates, original)
+			continue
+		}
+		claimed = append(claimed, key)
+		fresh = append(fresh, receipt)
+	}
+
+	if len(fresh) == 0 {
+		// Let later copies of this file point to the original post
+		p.releaseFingerprints(claimed)
+		claimed = nil
+	}
+	return fresh, duplicates, claimed, nil
+}
+
+// releaseFingerprints removes claimed fingerprint keys
+func (p *Plugin) releaseFingerprints(keys []string) {
+	for _, key := range keys {
+		if appErr := p.API.KVDelete(key); appErr != nil {
+			p.API.LogError("Failed to release receipt fingerprint", "key", key, "error", appErr.Error())
+		}
+	}
+}
+
+// sendDuplicateNotice replies to post with links to the posts that already
+// processed its receipts
+func (p *Plugin) sendDuplicateNotice(post *model.Post, duplicates []*fingerprintRecord) {
+	var message strings.Builder
+	message.WriteString("♻️ **Bu dekont daha önce işlendi.** Tekrar ödeme yapmadan önce kontrol edin:")
+
+	seen := map[string]bool{}
+	for _, original := range duplicates {
+		if seen[original.PostID] {
+			continue
+		}
+		seen[original.PostID] = true
+
+		message.WriteString(fmt.Sprintf("\n- [Orijinal gönderi](%s)", p.permalink(original.PostID)))
+		if original.ChannelID != post.ChannelId {
+			message.WriteString(" *(farklı bir kanalda)*")
+		}
+	}
+
+	notice := &model.Post{
+		ChannelId: post.ChannelId,
+		RootId:    threadRootID(post),
+		UserId:    p.botUserID,
+		Message:   message.String(),
+	}
+	notice.AddProp("dekont_duplicate_of", duplicates[0].PostID)
+
+	if _, appErr := p.API.CreatePost(notice); appErr != nil {
+		p.API.LogError("Failed to send duplicate notice", "error", appErr.Error())
+	}
+}
+
+// permalink returns a link to the post that works regardless of its team
+func (p *Plugin) permalink(postID string) string {
+	siteURL := ""
+	if config := p.API.GetConfig(); config != nil && config.ServiceSettings.SiteURL != nil {
+		siteURL = strings.TrimRight(*config.ServiceSettings.SiteURL, "/")
+	}
+	return siteURL + "/_redirect/pl/" + postID
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReceiptFingerprintKey(t *testing.T) {
+	date := time.Date(2025, 7, 15, 14, 30, 0, 0, time.UTC)
+	base := &Receipt{Sender: "Ahmet Kaya", Recipient: "ABC Şirketi", Amount: 150000, Currency: "TRY", Date: date}
+
+	tests := []struct {
+		name    string
+		receipt *Receipt
+		same    bool
+		empty   bool
+	}{
+		{
+			name:    "different spacing and case of the parties",
+			receipt: &Receipt{Sender: "AHMET  KAYA", Recipient: "abc şirketi", Amount: 150000, Currency: "TRY", Date: date},
+			same:    true,
+		},
+		{
+			name:    "different amount",
+			receipt: &Receipt{Sender: "Ahmet Kaya", Recipient: "ABC Şirketi", Amount: 150001, Currency: "TRY", Date: date},
+		},
+		{
+			name:    "different reference number",
+			receipt: &Receipt{Sender: "Ahmet Kaya", Recipient: "ABC Şirketi", Amount: 150000, Currency: "TRY", Date: date, ReferenceNumber: "123"},
+		},
+		{
+			name:    "no amount",
+			receipt: &Receipt{Recipient: "ABC Şirketi", Date: date},
+			empty:   true,
+		},
+		{
+			name:    "neither reference nor date",
+			receipt: &Receipt{Recipient: "ABC Şirketi", Amount: 150000},
+			empty:   true,
+		},
+	}
+
+	baseKey := receiptFingerprintKey(base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := receiptFingerprintKey(tt.receipt)
+			if tt.empty {
+				if key != "" {
+					t.Errorf("receiptFingerprintKey() = %q, want empty", key)
+				}
+				return
+			}
+			if (key == baseKey) != tt.same {
+				t.Errorf("receiptFingerprintKey() = %q, base %q, same = %v", key, baseKey, tt.same)
+			}
+		})
+	}
+}
+
+func TestReceiptFingerprintKeyByReference(t *testing.T) {
+	printed := &Receipt{Bank: "HalkBank", ReferenceNumber: "HB2025073001", Sender: "Ahmet Kaya", Amount: 150000,
+		Date: time.Date(2025, 7, 30, 16, 45, 0, 0, time.UTC)}
+	// The same transfer exported again, without the parsed date and sender
+	exported := &Receipt{Bank: "HalkBank", ReferenceNumber: "HB2025073001", Amount: 150000}
+	otherBank := &Receipt{Bank: "Akbank", ReferenceNumber: "HB2025073001", Amount: 150000}
+
+	if receiptFingerprintKey(printed) != receiptFingerprintKey(exported) {
+		t.Error("receiptFingerprintKey() differs for the same bank and reference number")
+	}
+	if receiptFingerprintKey(printed) == receiptFingerprintKey(otherBank) {
+		t.Error("receiptFingerprintKey() is equal for the same reference number of different banks")
+	}
+}
+
+func TestClaimReceipts(t *testing.T) {
+	api, _ := newKVTestAPI()
+	siteURL := "https://chat.example.com/"
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}}).Maybe()
+	p := &Plugin{botUserID: "bot"}
+	p.SetAPI(api)
+
+	receipt := func() *Receipt {
+		return &Receipt{Recipient: "ABC Şirketi", Amount: 150000, Currency: "TRY",
+			Date: time.Date(2025, 7, 15, 0, 0, 0, 0, time.UTC)}
+	}
+	original := &model.Post{Id: "original", ChannelId: "finance"}
+
+	fresh, duplicates, claimed, err := p.claimReceipts(original, []byte("file"), []*Receipt{receipt()})
+	if err != nil || len(fresh) != 1 || len(duplicates) != 0 || len(claimed) != 2 {
+		t.Fatalf("claimReceipts() = %d fresh, %d duplicates, %d claimed, %v", len(fresh), len(duplicates), len(claimed), err)
+	}
+
+	t.Run("same post processed again", func(t *testing.T) {
+		fresh, duplicates, _, err := p.claimReceipts(original, []byte("file"), []*Receipt{receipt()})
+		if err != nil || len(fresh) != 1 || len(duplicates) != 0 {
+			t.Errorf("claimReceipts() = %d fresh, %d duplicates, %v, want 1 fresh", len(fresh), len(duplicates), err)
+		}
+	})
+
+	t.Run("same file in another channel", func(t *testing.T) {
+		fresh, duplicates, _, err := p.claimReceipts(&model.Post{Id: "copy", ChannelId: "payments"}, []byte("file"), []*Receipt{receipt()})
+		if err != nil || len(fresh) != 0 || len(duplicates) != 1 || duplicates[0].PostID != "original" {
+			t.Errorf("claimReceipts() = %d fresh, %v duplicates, %v, want the original post", len(fresh), duplicates, err)
+		}
+	})
+
+	t.Run("same receipt in a different file", func(t *testing.T) {
+		other := &Receipt{Recipient: "XYZ Ltd.", Amount: 5000, Currency: "TRY",
+			Date: time.Date(2025, 7, 16, 0, 0, 0, 0, time.UTC)}
+		fresh, duplicates, _, err := p.claimReceipts(&model.Post{Id: "scan", ChannelId: "finance"}, []byte("scan"), []*Receipt{receipt(), other})
+		if err != nil || len(fresh) != 1 || fresh[0] != other || len(duplicates) != 1 {
+			t.Errorf("claimReceipts() = %v fresh, %v duplicates, %v, want only the new receipt", fresh, duplicates, err)
+		}
+	})
+
+	t.Run("notice links the original post", func(t *testing.T) {
+		api.On("CreatePost", mock.MatchedBy(func(notice *model.Post) bool {
+			return notice.UserId == "bot" && notice.RootId == "copy" &&
+				strings.Contains(notice.Message, "(https://chat.example.com/_redirect/pl/original) *(farklı bir kanalda)*")
+		})).Return(nil, nil).Once()
+
+		p.sendDuplicateNotice(&model.Post{Id: "copy", ChannelId: "payments"},
+			[]*fingerprintRecord{{PostID: "original", ChannelID: "finance"}})
+		api.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Export file formats
+const (
+	exportFormatCSV  = "csv"
+	exportFormatXLSX = "xlsx"
+)
+
+// exportColumns are the column headers of an export. The amount column is
+// written as a number so spreadsheets can sum it.
+var exportColumns = []string{
+	"Tarih", "Banka", "Gönderen", "Gönderen IBAN", "Alıcı", "Alıcı IBAN",
+	"Tutar", "Para Birimi", "Açıklama", "Referans No", "Gönderi",
+}
+
+// exportAmountColumn is the index of the amount in exportColumns
+const exportAmountColumn = 6
+
+// exportContentTypes are the MIME types of the export formats
+var exportContentTypes = map[string]string{
+	exportFormatCSV:  "text/csv; charset=utf-8",
+	exportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// exportRows returns the rows of an export of transactions, header first
+func (p *Plugin) exportRows(transactions []*Transaction) [][]string {
+	rows := [][]string{exportColumns}
+	for _, tx := range transactions {
+		receipt := tx.Receipt
+		if receipt == nil {
+			receipt = &Receipt{}
+		}
+		amount := ""
+		if receipt.Amount != 0 {
+			amount = receipt.Amount.String()
+		}
+		rows = append(rows, []string{
+			tx.Time().Format("02.01.2006"),
+			receipt.Bank,
+			receipt.Sender,
+			receipt.SenderIBAN,
+			receipt.Recipient,
+			receipt.RecipientIBAN,
+			amount,
+			receipt.Currency,
+			receipt.Description,
+			receipt.ReferenceNumber,
+			p.permalink(tx.PostID),
+		})
+	}
+	return rows
+}
+
+// buildExport renders the transactions in the given format
+func (p *Plugin) buildExport(transactions []*Transaction, format string) ([]byte, error) {
+	rows := p.exportRows(transactions)
+	switch format {
+	case exportFormatCSV:
+		return writeCSV(rows)
+	case exportFormatXLSX:
+		return writeXLSX(rows)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// exportFileName names the export of the transactions between from and to
+func exportFileName(format string, from, to time.Time) string {
+	return fmt.Sprintf("dekontlar_%s_%s.%s", from.Format(dayIndexLayout), to.Format(dayIndexLayout), format)
+}
+
+// writeCSV writes the rows as CSV with a byte order mark, which spreadsheet
+// applications need to read Turkish letters as UTF-8. Cells read from the
+// receipts are escaped so that spreadsheets do not run them as formulas.
+func writeCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\uFEFF")
+	w := csv.NewWriter(&buf)
+	for i, row := range rows {
+		escaped := make([]string, len(row))
+		for j, value := range row {
+			if i > 0 && j == exportAmountColumn {
+				escaped[j] = value
+				continue
+			}
+			escaped[j] = escapeCSVFormula(value)
+		}
+		if err := w.Write(escaped); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// escapeCSVFormula prefixes a value spreadsheets would read as a formula
+// with a quote
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Parts of a minimal XLSX workbook with a single sheet
+const (
+	xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Dekontlar" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+)
+
+// writeXLSX writes the rows as a single sheet XLSX workbook. Cells are inline
+// strings, except for the amounts of the data rows, which are numbers.
+func writeXLSX(rows [][]string) ([]byte, error) {
+	var sheet strings.Builder
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%d">`, i+1)
+		for j, value := range row {
+			if value == "" {
+				continue
+			}
+			ref := xlsxCellRef(j, i)
+			if i > 0 && j == exportAmountColumn {
+				fmt.Fprintf(&sheet, `<c r="%s"><v>%s</v></c>`, ref, value)
+				continue
+			}
+			fmt.Fprintf(&sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(&sheet, []byte(value)); err != nil {
+				return nil, err
+			}
+			sheet.WriteString(`</t></is></c>`)
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData></worksheet>`)
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	} {
+		f, err := w.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xlsxCellRef returns the A1 style reference of a zero-based cell position
+func xlsxCellRef(column, row int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return fmt.Sprintf("%s%d", name, row+1)
+}
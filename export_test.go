@@ -0,0 +1,184 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWriteCSV(t *testing.T) {
+	data, err := writeCSV([][]string{exportColumns, {"01.07.2025", "Akbank", "Ahmet, Kaya", "", "", "", "1500.00"}})
+	if err != nil {
+		t.Fatalf("writeCSV() error = %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("\uFEFF")) {
+		t.Error("writeCSV() has no byte order mark")
+	}
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\uFEFF"))))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("csv.ReadAll() error = %v", err)
+	}
+	if len(rows) != 2 || rows[0][4] != "Alıcı" || rows[1][2] != "Ahmet, Kaya" || rows[1][6] != "1500.00" {
+		t.Errorf("writeCSV() rows = %q", rows)
+	}
+}
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	data, err := writeCSV([][]string{exportColumns, {
+		"01.07.2025", "Akbank", `=HYPERLINK("https://evil.example.com","Tıkla")`, "", "+90 555", "", "-1500.00",
+		"TRY", "@SUM(A1)", "\tREF", "-x",
+	}})
+	if err != nil {
+		t.Fatalf("writeCSV() error = %v", err)
+	}
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\uFEFF"))))
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("csv.ReadAll() error = %v", err)
+	}
+	expected := []string{
+		"01.07.2025", "Akbank", `'=HYPERLINK("https://evil.example.com","Tıkla")`, "", "'+90 555", "", "-1500.00",
+		"TRY", "'@SUM(A1)", "'\tREF", "'-x",
+	}
+	for i, want := range expected {
+		if rows[1][i] != want {
+			t.Errorf("writeCSV() cell %d = %q, want %q", i, rows[1][i], want)
+		}
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	data, err := writeXLSX([][]string{exportColumns, {"01.07.2025", "Akbank", "A & B <Ltd>", "", "", "", "1500.00"}})
+	if err != nil {
+		t.Fatalf("writeXLSX() error = %v", err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("writeXLSX() is missing %s", name)
+		}
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<c r="E1" t="inlineStr"><is><t xml:space="preserve">Alıcı</t></is></c>`,
+		`<c r="C2" t="inlineStr"><is><t xml:space="preserve">A &amp; B &lt;Ltd&gt;</t></is></c>`,
+		`<c r="G2"><v>1500.00</v></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("sheet1.xml = %s, want it to contain %s", sheet, expected)
+		}
+	}
+	if strings.Contains(sheet, `r="D2"`) {
+		t.Error("sheet1.xml contains an empty cell")
+	}
+}
+
+func TestXLSXCellRef(t *testing.T) {
+	tests := []struct {
+		column, row int
+		expected    string
+	}{
+		{0, 0, "A1"},
+		{10, 4, "K5"},
+		{25, 0, "Z1"},
+		{26, 9, "AA10"},
+		{701, 0, "ZZ1"},
+		{702, 0, "AAA1"},
+	}
+
+	for _, tt := range tests {
+		if result := xlsxCellRef(tt.column, tt.row); result != tt.expected {
+			t.Errorf("xlsxCellRef(%d, %d) = %q, want %q", tt.column, tt.row, result, tt.expected)
+		}
+	}
+}
+
+func TestParseExportPeriod(t *testing.T) {
+	now := time.Date(2025, 8, 15, 14, 30, 0, 0, istanbul)
+
+	tests := []struct {
+		name   string
+		params []string
+		from   string
+		to     string
+		ok     bool
+	}{
+		{name: "default period", from: "17.07.2025 00:00", to: "15.08.2025 14:30", ok: true},
+		{name: "days", params: []string{"7"}, from: "09.08.2025 00:00", to: "15.08.2025 14:30", ok: true},
+		{name: "date range", params: []string{"01.07.2025", "31.07.2025"}, from: "01.07.2025 00:00", to: "31.07.2025 23:59", ok: true},
+		{name: "invalid days", params: []string{"0"}},
+		{name: "invalid date", params: []string{"01.07.2025", "yarın"}},
+		{name: "reversed range", params: []string{"31.07.2025", "01.07.2025"}},
+		{name: "too many parameters", params: []string{"1", "2", "3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, ok := parseExportPeriod(tt.params, now)
+			if ok != tt.ok {
+				t.Fatalf("parseExportPeriod(%q) ok = %v, want %v", tt.params, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if result := from.Format("02.01.2006 15:04") + " - " + to.Format("02.01.2006 15:04"); result != tt.from+" - "+tt.to {
+				t.Errorf("parseExportPeriod(%q) = %s, want %s - %s", tt.params, result, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestExecuteCommandExport(t *testing.T) {
+	p, api := newCommandTestPlugin(t)
+	api.On("UploadFile", mock.MatchedBy(func(data []byte) bool {
+		return bytes.Contains(data, []byte("XYZ Ltd.")) && !bytes.Contains(data, []byte("ABC Şirketi"))
+	}), "finance", mock.MatchedBy(func(name string) bool {
+		return strings.HasPrefix(name, "dekontlar_") && strings.HasSuffix(name, ".csv")
+	})).Return(&model.FileInfo{Id: "export"}, nil).Once()
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "finance" && post.UserId == "bot" && len(post.FileIds) == 1 && post.FileIds[0] == "export" &&
+			strings.Contains(post.Message, "dekont dökümü** (1 dekont)")
+	})).Return(nil, nil).Once()
+
+	response, _ := p.ExecuteCommand(nil, &model.CommandArgs{Command: "/dekont export CSV 7", ChannelId: "finance", UserId: "user"})
+	if !strings.Contains(response.Text, ".csv` kanala eklendi") {
+		t.Errorf("ExecuteCommand(export) = %q", response.Text)
+	}
+
+	response, _ = p.ExecuteCommand(nil, &model.CommandArgs{Command: "/dekont export csv 01.01.2020 31.01.2020", ChannelId: "finance", UserId: "user"})
+	if response.Text != "01.01.2020 - 31.01.2020 arasında bu kanalda işlenmiş dekont yok." {
+		t.Errorf("ExecuteCommand(export) of an empty period = %q", response.Text)
+	}
+
+	response, _ = p.ExecuteCommand(nil, &model.CommandArgs{Command: "/dekont export pdf", ChannelId: "finance", UserId: "user"})
+	if response.Text != exportUsage {
+		t.Errorf("ExecuteCommand(export pdf) = %q, want usage", response.Text)
+	}
+	api.AssertExpectations(t)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// formatReceipt renders the receipt fields as markdown. It returns an empty
+// string for a nil receipt.
+func formatReceipt(receipt *Receipt, config *Configuration) string {
+	if receipt == nil {
+		return ""
+	}
+
+	var result strings.Builder
+	// writeField writes a field line, flagging low confidence values when
+	// configured
+	writeField := func(label, field, value string) {
+		if value == "" {
+			return
+		}
+		result.WriteString(fmt.Sprintf("**%s**: %s%s\n", label, value, confidenceMarker(receipt, field, config)))
+	}
+
+	writeField("Açıklama", fieldDescription, receipt.Description)
+	writeField("Alıcı", fieldRecipient, receipt.Recipient)
+	if receipt.RecipientIBAN != "" {
+		writeField("Alıcı IBAN", fieldRecipientIBAN, formatAccount(receipt.RecipientIBAN, config))
+	}
+	writeField("Gönderen", fieldSender, receipt.Sender)
+	if receipt.SenderIBAN != "" {
+		writeField("Gönderen IBAN", fieldSenderIBAN, formatAccount(receipt.SenderIBAN, config))
+	}
+	writeField("İşlem Tutarı", fieldAmount, formatAmount(receipt, config))
+
+	chargeCurrency := receipt.Currency
+	if receipt.ChargeCurrency != "" {
+		chargeCurrency = receipt.ChargeCurrency
+	}
+	for _, charge := range []struct {
+		label  string
+		field  string
+		amount Amount
+	}{{"Masraf", fieldFee, receipt.Fee}, {"Komisyon", fieldCommission, receipt.Commission}, {"BSMV", fieldTax, receipt.Tax}} {
+		if charge.amount != 0 {
+			writeField(charge.label, charge.field, formatMoney(charge.amount, chargeCurrency, config.AmountLocale))
+		}
+	}
+	if receipt.Total != 0 {
+		total := formatMoney(receipt.Total, chargeCurrency, config.AmountLocale)
+		if receipt.TotalMismatch {
+			total += " ⚠️ *Tutar ve masraflar toplamı tutmuyor*"
+		}
+		writeField("Toplam Tutar", fieldTotal, total)
+	}
+	if receipt.LocalAmount != 0 {
+		writeField("TL Karşılığı", fieldLocalAmount, formatMoney(receipt.LocalAmount, defaultCurrency, config.AmountLocale))
+	}
+	if receipt.ExchangeRate != 0 {
+		writeField("Kur", fieldExchangeRate, formatExchangeRate(receipt, config))
+	}
+	writeField("İşlem Tarihi", fieldDate, formatDate(receipt))
+	writeField("Referans No", fieldReference, receipt.ReferenceNumber)
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// confidenceMarker returns a warning marker for a field read by a low
+// confidence rule when FlagLowConfidenceFields is enabled
+func confidenceMarker(receipt *Receipt, field string, config *Configuration) string {
+	if !config.FlagLowConfidenceFields || !receipt.lowConfidence(field) {
+		return ""
+	}
+	return " ⚠️"
+}
+
+// formatAmount renders the receipt amount with its currency in the configured
+// locale. An amount that could not be parsed, and so has no currency, is
+// shown as printed.
+func formatAmount(receipt *Receipt, config *Configuration) string {
+	if receipt.AmountText == "" {
+		return ""
+	}
+	if _, ok := parseMoney(receipt.AmountText, receipt.Currency); receipt.Currency == "" || !ok {
+		return receipt.AmountText
+	}
+	return formatMoney(receipt.Amount, receipt.Currency, config.AmountLocale)
+}
+
+// formatAccount renders an IBAN, masked unless configured otherwise, with
+// the name of the bank holding it
+func formatAccount(iban string, config *Configuration) string {
+	account := formatIBAN(iban, config.ShowFullIBAN)
+	if bank := ibanBank(iban); bank != "" {
+		account += fmt.Sprintf(" (%s)", bank)
+	}
+	return account
+}
+
+// formatDate renders the transaction date in Istanbul time, with the time of
+// day when the receipt printed one. A date that could not be parsed is shown
+// as printed.
+func formatDate(receipt *Receipt) string {
+	if receipt.Date.IsZero() {
+		return receipt.DateText
+	}
+
+	date := receipt.Date.In(istanbul)
+	switch {
+	case date.Second() != 0:
+		return date.Format("02.01.2006 15:04:05")
+	case date.Hour() != 0 || date.Minute() != 0:
+		return date.Format("02.01.2006 15:04")
+	default:
+		return date.Format("02.01.2006")
+	}
+}
+
+// formatExchangeRate renders the rate as the lira price of one unit of the
+// receipt currency
+func formatExchangeRate(receipt *Receipt, config *Configuration) string {
+	return fmt.Sprintf("1 %s = %s %s",
+		currencyLabel(receipt.Currency, config.AmountLocale),
+		receipt.ExchangeRate.Format(config.AmountLocale),
+		currencyLabel(defaultCurrency, config.AmountLocale))
+}
+
+// formatType renders the transaction type for tables, leaving unknown types
+// blank
+func formatType(typ TransactionType) string {
+	if typ == "" {
+		return ""
+	}
+	return typ.Label()
+}
+
+// formatBank renders the identified bank with its confidence, flagging
+// identifications below the configured threshold for review
+func formatBank(receipt *Receipt, config *Configuration) string {
+	if receipt.Bank == "" {
+		return "**Banka**: Tespit edilemedi ⚠️"
+	}
+
+	line := fmt.Sprintf("**Banka**: %s (%%%d güven)", receipt.Bank, int(math.Round(receipt.BankConfidence*100)))
+	if needsReview(receipt, config.BankConfidenceThreshold) {
+		line += " ⚠️ *Lütfen kontrol edin*"
+	}
+	return line
+}
+
+// formatReceiptTable renders several receipts as a markdown table, one row
+// per receipt
+func formatReceiptTable(receipts []*Receipt, config *Configuration) string {
+	var result strings.Builder
+
+	result.WriteString("| # | Banka | Tür | Tarih | Gönderen | Alıcı | Açıklama | Tutar |\n")
+	result.WriteString("|---|---|---|---|---|---|---|---:|\n")
+	for i, receipt := range receipts {
+		result.WriteString(fmt.Sprintf("| %d | %s\n", i+1, receiptTableCells(receipt, config)))
+	}
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// formatAttachmentTable renders the receipts of several attachments as a
+// markdown table, one row per receipt with the name of its file
+func formatAttachmentTable(results []*attachmentResult, config *Configuration) string {
+	var result strings.Builder
+
+	result.WriteString("| # | Dosya | Banka | Tür | Tarih | Gönderen | Alıcı | Açıklama | Tutar |\n")
+	result.WriteString("|---|---|---|---|---|---|---|---|---:|\n")
+	row := 0
+	for _, attachment := range results {
+		for _, receipt := range attachment.receipts {
+			row++
+			result.WriteString(fmt.Sprintf("| %d | %s | %s\n", row,
+				escapeTableCell(attachment.fileInfo.Name),
+				receiptTableCells(receipt, config)))
+		}
+	}
+
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// receiptTableCells renders the bank, type, date, parties, description and
+// amount cells of a receipt table row, closing the row
+func receiptTableCells(receipt *Receipt, config *Configuration) string {
+	bank := receipt.Bank
+	if needsReview(receipt, config.BankConfidenceThreshold) {
+		bank = strings.TrimSpace(bank + " ⚠️")
+	}
+	amount := formatAmount(receipt, config)
+	if receipt.LocalAmount != 0 {
+		amount += fmt.Sprintf(" (%s)", formatMoney(receipt.LocalAmount, defaultCurrency, config.AmountLocale))
+	}
+	return fmt.Sprintf("%s | %s | %s | %s | %s | %s | %s |",
+		escapeTableCell(bank),
+		formatType(receipt.Type),
+		escapeTableCell(formatDate(receipt)),
+		escapeTableCell(receipt.Sender),
+		escapeTableCell(receipt.Recipient),
+		escapeTableCell(receipt.Description),
+		escapeTableCell(amount))
+}
+
+// formatGrandTotal renders the sum of the receipt amounts, one sum per
+// currency
+func formatGrandTotal(receipts []*Receipt, config *Configuration) string {
+	return "**Genel Toplam**: " + formatTotals(receiptTotals(receipts), config)
+}
+
+// formatTotals renders totals per currency as a sum, sorted by currency
+func formatTotals(totals map[string]Amount, config *Configuration) string {
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	sums := make([]string, len(currencies))
+	for i, currency := range currencies {
+		sums[i] = formatMoney(totals[currency], currency, config.AmountLocale)
+	}
+	return strings.Join(sums, " + ")
+}
+
+// formatAttachmentErrors lists the attachments whose receipts are not shown
+// and why
+func formatAttachmentErrors(results []*attachmentResult) string {
+	var result strings.Builder
+	result.WriteString("**İşlenemeyen dosyalar**\n")
+	for _, attachment := range results {
+		if failure := attachment.failure(); failure != "" {
+			result.WriteString(fmt.Sprintf("- %s: %s\n", attachment.fileInfo.Name, failure))
+		}
+	}
+	return strings.TrimRight(result.String(), "\n")
+}
+
+// escapeTableCell keeps a value from breaking the markdown table layout
+func escapeTableCell(value string) string {
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+// formatMessage renders the receipts with the configured prefix, processing
+// timestamp and credits footer. A single receipt is rendered field by field,
+// several receipts as a table.
+func formatMessage(receipts []*Receipt, config *Configuration) string {
+	return formatAttachments([]*attachmentResult{{receipts: receipts}}, config)
+}
+
+// formatAttachments renders the receipts of all attachments of a post like
+// formatMessage. Receipts of several files are rendered as one table with
+// the file names and a grand total, followed by the files without receipts.
+func formatAttachments(results []*attachmentResult, config *Configuration) string {
+	var fullMessage strings.Builder
+
+	if config.CustomMessagePrefix != "" {
+		fullMessage.WriteString(config.CustomMessagePrefix)
+		fullMessage.WriteString("\n\n")
+	}
+
+	files := 0
+	failed := false
+	for _, attachment := range results {
+		if len(attachment.receipts) > 0 {
+			files++
+		} else if attachment.failure() != "" {
+			failed = true
+		}
+	}
+	receipts := attachmentReceipts(results)
+
+	switch {
+	case files > 1:
+		fullMessage.WriteString(fmt.Sprintf("**%d dosyada %d dekont bulundu**\n\n", files, len(receipts)))
+		fullMessage.WriteString(formatAttachmentTable(results, config))
+		fullMessage.WriteString("\n\n")
+		fullMessage.WriteString(formatGrandTotal(receipts, config))
+	case len(receipts) == 1:
+		fullMessage.WriteString(formatBank(receipts[0], config))
+		fullMessage.WriteString("\n")
+		if receipts[0].Type != "" {
+			fullMessage.WriteString(fmt.Sprintf("**İşlem Türü**: %s%s\n", receipts[0].Type.Label(), confidenceMarker(receipts[0], fieldType, config)))
+		}
+		fullMessage.WriteString(formatReceipt(receipts[0], config))
+	case len(receipts) > 1:
+		fullMessage.WriteString(fmt.Sprintf("**%d dekont bulundu**\n\n", len(receipts)))
+		fullMessage.WriteString(formatReceiptTable(receipts, config))
+	}
+
+	if failed {
+		if len(receipts) > 0 {
+			fullMessage.WriteString("\n\n")
+		}
+		fullMessage.WriteString(formatAttachmentErrors(results))
+	}
+
+	if config.IncludeTimestamp {
+		timestamp := time.Now().Format("02.01.2006 15:04:05")
+		fullMessage.WriteString(fmt.Sprintf("\n\n*İşlenme Zamanı: %s*", timestamp))
+	}
+
+	// Add credits footer (if not hidden)
+	if !config.HideCredits {
+		fullMessage.WriteString("\n\n---\n*Mattermost PDF Parser Plugin by SkyLostTR* 🚀")
+	}
+
+	return fullMessage.String()
+}
+
+// attachmentReceipts returns the receipts of all attachments in file order
+func attachmentReceipts(results []*attachmentResult) []*Receipt {
+	var receipts []*Receipt
+	for _, attachment := range results {
+		receipts = append(receipts, attachment.receipts...)
+	}
+	return receipts
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// turkishIBANLength is the length of a TR IBAN without spaces
+const turkishIBANLength = 26
+
+var (
+	// reIBAN matches a TR IBAN printed with or without grouping spaces
+	reIBAN = regexp.MustCompile(`(?i)\bTR(?:[ \t]?[0-9]){24}\b`)
+
+	// Labels telling whose account an IBAN is. The recipient labels are
+	// checked first, as GÖNDERİLEN (sent to) also starts like GÖNDEREN.
+	reRecipientIBANLabel = regexp.MustCompile(`(?i)AL[Iı]C[Iı]|ALACAKLI|G[ÖO]NDER[İIıi]LEN|KAR[ŞS][Iı]\s*TARAF|LEHDAR`)
+	reSenderIBANLabel    = regexp.MustCompile(`(?i)G[ÖO]NDEREN|BOR[ÇC]LU|HESAP\s*SAH[İIıi]B[İIıi]`)
+)
+
+// ibanBanks maps the five digit bank codes of TR IBANs to bank names
+var ibanBanks = map[string]string{
+	"00010": ziraatProfile.name,
+	"00012": halkBankProfile.name,
+	"00015": vakifBankProfile.name,
+	"00046": akbankProfile.name,
+	"00062": garantiProfile.name,
+	"00064": isBankasiProfile.name,
+	"00067": yapiKrediProfile.name,
+	"00205": kuveytTurkProfile.name,
+	"00032": "TEB",
+	"00059": "Şekerbank",
+	"00099": "ING",
+	"00103": "Fibabanka",
+	"00111": "QNB",
+	"00123": "HSBC",
+	"00134": "DenizBank",
+	"00135": "Anadolubank",
+	"00146": "Odeabank",
+	"00203": "Albaraka Türk",
+	"00206": "Türkiye Finans",
+	"00209": "Ziraat Katılım",
+	"00210": "Vakıf Katılım",
+	"00211": "Emlak Katılım",
+}
+
+// ibanMatch is an IBAN found in a receipt and whether a label assigned it
+// to its party
+type ibanMatch struct {
+	iban     string
+	labelled bool
+}
+
+// source returns the provenance of the IBAN. The checksum makes any match
+// certain to be an IBAN, but only a label makes its party certain.
+func (m ibanMatch) source() FieldSource {
+	if m.labelled {
+		return FieldSource{Rule: "iban.label", Confidence: confidenceHigh}
+	}
+	return FieldSource{Rule: "iban.order", Confidence: confidenceLow}
+}
+
+// extractIBANs returns the valid sender and recipient IBANs of a receipt.
+// IBANs are assigned by the label on their line, or on the line before.
+// Unlabelled IBANs fill the remaining roles in order, as receipts list the
+// sender before the recipient.
+func extractIBANs(text string) (sender, recipient ibanMatch) {
+	var unlabelled []string
+	for _, loc := range reIBAN.FindAllStringIndex(text, -1) {
+		iban := normalizeIBAN(text[loc[0]:loc[1]])
+		if !validIBAN(iban) {
+			continue
+		}
+
+		switch label := ibanLabel(text, loc[0]); {
+		case reRecipientIBANLabel.MatchString(label):
+			if recipient.iban == "" {
+				recipient = ibanMatch{iban: iban, labelled: true}
+			}
+		case reSenderIBANLabel.MatchString(label):
+			if sender.iban == "" {
+				sender = ibanMatch{iban: iban, labelled: true}
+			}
+		default:
+			unlabelled = append(unlabelled, iban)
+		}
+	}
+
+	for _, iban := range unlabelled {
+		switch {
+		case iban == sender.iban || iban == recipient.iban:
+		case sender.iban == "":
+			sender = ibanMatch{iban: iban}
+		case recipient.iban == "":
+			recipient = ibanMatch{iban: iban}
+		}
+	}
+	return sender, recipient
+}
+
+// ibanLabel returns the text before the IBAN at offset on its line, or the
+// line before when that text names neither party and that line has no IBAN
+// of its own
+func ibanLabel(text string, offset int) string {
+	lineStart := strings.LastIndex(text[:offset], "\n") + 1
+	label := text[lineStart:offset]
+	if reRecipientIBANLabel.MatchString(label) || reSenderIBANLabel.MatchString(label) || lineStart == 0 {
+		return label
+	}
+
+	previous := text[strings.LastIndex(text[:lineStart-1], "\n")+1 : lineStart-1]
+	if reIBAN.MatchString(previous) {
+		return label
+	}
+	return previous
+}
+
+// normalizeIBAN removes grouping spaces and upper-cases the country code
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// validIBAN checks the length of a TR IBAN and the ISO 13616 mod-97
+// checksum of any IBAN
+func validIBAN(iban string) bool {
+	if len(iban) < 5 || (strings.HasPrefix(iban, "TR") && len(iban) != turkishIBANLength) {
+		return false
+	}
+
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// ibanBank returns the name of the bank holding a TR IBAN, or an empty
+// string for unknown bank codes
+func ibanBank(iban string) string {
+	if len(iban) != turkishIBANLength || !strings.HasPrefix(iban, "TR") {
+		return ""
+	}
+	return ibanBanks[iban[4:9]]
+}
+
+// formatIBAN renders an IBAN in groups of four, or masked to its country
+// code, check digits and last four digits such as "TR12 **** **** 1234"
+func formatIBAN(iban string, full bool) string {
+	if len(iban) < 8 {
+		return iban
+	}
+	if !full {
+		return iban[:4] + " **** **** " + iban[len(iban)-4:]
+	}
+
+	var groups []string
+	for start := 0; start < len(iban); start += 4 {
+		end := start + 4
+		if end > len(iban) {
+			end = len(iban)
+		}
+		groups = append(groups, iban[start:end])
+	}
+	return strings.Join(groups, " ")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Valid TR IBANs of Ziraat Bankası, Türkiye İş Bankası and Kuveyt Türk
+const (
+	testZiraatIBAN = "TR830001000000000123456789"
+	testIsBankIBAN = "TR950006400000001234567890"
+	testKuveytIBAN = "TR610020500000009876543210"
+)
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		name     string
+		iban     string
+		expected bool
+	}{
+		{name: "valid TR IBAN", iban: testZiraatIBAN, expected: true},
+		{name: "valid foreign IBAN", iban: "DE89370400440532013000", expected: true},
+		{name: "wrong check digits", iban: "TR840001000000000123456789", expected: false},
+		{name: "swapped digits", iban: "TR830001000000000123456798", expected: false},
+		{name: "short TR IBAN", iban: "TR83000100000000012345678", expected: false},
+		{name: "invalid characters", iban: "TR83-001000000000123456789", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := validIBAN(tt.iban); result != tt.expected {
+				t.Errorf("validIBAN(%q) = %v, want %v", tt.iban, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractIBANs(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		sender    string
+		recipient string
+	}{
+		{
+			name:      "labelled on the same line",
+			input:     "ALICI IBAN: TR95 0006 4000 0000 1234 5678 90\nGÖNDEREN IBAN: TR83 0001 0000 0000 0123 4567 89",
+			sender:    testZiraatIBAN,
+			recipient: testIsBankIBAN,
+		},
+		{
+			name:      "labelled on the line before",
+			input:     "GÖNDEREN BİLGİLERİ\n" + testZiraatIBAN + "\nALICI BİLGİLERİ\n" + testIsBankIBAN,
+			sender:    testZiraatIBAN,
+			recipient: testIsBankIBAN,
+		},
+		{
+			name:      "Kuveyt Türk sent to label is the recipient",
+			input:     "Gönderilen IBAN: " + testIsBankIBAN + "\nGönderen IBAN: " + testKuveytIBAN,
+			sender:    testKuveytIBAN,
+			recipient: testIsBankIBAN,
+		},
+		{
+			name:      "unlabelled in printed order",
+			input:     "HESAP: " + testZiraatIBAN + "\nHESAP: " + testIsBankIBAN,
+			sender:    testZiraatIBAN,
+			recipient: testIsBankIBAN,
+		},
+		{
+			name:      "invalid checksum is ignored",
+			input:     "ALICI IBAN: TR840001000000000123456789\nGÖNDEREN IBAN: " + testIsBankIBAN,
+			sender:    testIsBankIBAN,
+			recipient: "",
+		},
+		{
+			name:  "no IBAN",
+			input: "ALICI: Test User\nTUTAR: 100,00 TL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, recipient := extractIBANs(tt.input)
+			if sender.iban != tt.sender || recipient.iban != tt.recipient {
+				t.Errorf("extractIBANs() = %q, %q, want %q, %q", sender.iban, recipient.iban, tt.sender, tt.recipient)
+			}
+		})
+	}
+}
+
+func TestFormatAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		iban     string
+		full     bool
+		expected string
+	}{
+		{name: "masked", iban: testZiraatIBAN, expected: "TR83 **** **** 6789 (Ziraat Bankası)"},
+		{name: "full", iban: testIsBankIBAN, full: true, expected: "TR95 0006 4000 0000 1234 5678 90 (Türkiye İş Bankası)"},
+		{name: "unknown bank code", iban: "DE89370400440532013000", expected: "DE89 **** **** 3000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatAccount(tt.iban, &Configuration{ShowFullIBAN: tt.full})
+			if result != tt.expected {
+				t.Errorf("formatAccount() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatReceiptWithIBANs(t *testing.T) {
+	receipt := extractReceipt("ALICI : Test User\nALICI IBAN : " + testIsBankIBAN +
+		"\nGÖNDEREN : Ahmet Kaya\nGÖNDEREN IBAN : " + testZiraatIBAN + "\nTUTAR : 100,00 TL")
+
+	result := formatReceipt(receipt, &Configuration{})
+	expected := "**Alıcı**: Test User\n**Alıcı IBAN**: TR95 **** **** 7890 (Türkiye İş Bankası)\n" +
+		"**Gönderen**: Ahmet Kaya\n**Gönderen IBAN**: TR83 **** **** 6789 (Ziraat Bankası)"
+	if !strings.HasPrefix(result, expected) {
+		t.Errorf("formatReceipt() = %q, want prefix %q", result, expected)
+	}
+	if strings.Contains(result, "0006 4000") {
+		t.Errorf("formatReceipt() = %q, want masked IBANs", result)
+	}
+}
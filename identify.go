@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"regexp"
+)
+
+// Weights of the bank identification signals. A bank's name printed as a
+// logo is the strongest hint but also appears for counterparty banks, so it
+// is combined with the identifiers found in receipt footers.
+const (
+	logoWeight   = 0.5
+	swiftWeight  = 0.4
+	taxIDWeight  = 0.4
+	footerWeight = 0.3
+	layoutWeight = 0.3
+)
+
+// bankProfile describes how to recognize the receipts of a bank
+type bankProfile struct {
+	name      string
+	logo      string   // bank name as printed in the logo or header
+	legalName string   // full trade name printed in the footer
+	domain    string   // website printed in the footer
+	swift     string   // SWIFT/BIC code, branch suffix optional
+	taxIDs    []string // VKN numbers, also matched inside MERSİS numbers
+}
+
+// signals builds the detection signals of the profile
+func (bp bankProfile) signals() []detectSignal {
+	result := []detectSignal{signal(bp.logo, logoWeight)}
+	if bp.legalName != "" {
+		result = append(result, signal(bp.legalName, footerWeight))
+	}
+	if bp.domain != "" {
+		result = append(result, signal(regexp.QuoteMeta(bp.domain), footerWeight))
+	}
+	if bp.swift != "" {
+		result = append(result, signal(bp.swift+`(?:[A-Z0-9]{3})?\b`, swiftWeight))
+	}
+	for _, taxID := range bp.taxIDs {
+		// MERSİS numbers are the VKN wrapped as 0 + VKN + 5 digits
+		result = append(result, signal(`\b0?`+taxID+`(?:[0-9]{5})?\b`, taxIDWeight))
+	}
+	return result
+}
+
+// Profiles of the supported banks
+var (
+	vakifBankProfile = bankProfile{
+		name:      "VakıfBank",
+		logo:      `VAK[İIıi]F\s*BANK|VAK[İIıi]FLAR\s*BANKASI`,
+		legalName: `T\.?\s*VAK[İIıi]FLAR\s*BANKASI\s*T\.?\s*A\.?\s*O`,
+		domain:    "vakifbank.com.tr",
+		swift:     "TVBATR2A",
+		taxIDs:    []string{"9220034970"},
+	}
+	yapiKrediProfile = bankProfile{
+		name:      "Yapı Kredi",
+		logo:      `YAPI\s*KRED[İIıi]`,
+		legalName: `YAPI\s*VE\s*KRED[İIıi]\s*BANKASI`,
+		domain:    "yapikredi.com.tr",
+		swift:     "YAPITRIS",
+		taxIDs:    []string{"9370020892"},
+	}
+	kuveytTurkProfile = bankProfile{
+		name:      "Kuveyt Türk",
+		logo:      `KUVEYT\s*T[ÜU]RK`,
+		legalName: `KUVEYT\s*T[ÜU]RK\s*KATILIM\s*BANKASI`,
+		domain:    "kuveytturk.com.tr",
+		swift:     "KTEFTRIS",
+	}
+	halkBankProfile = bankProfile{
+		name:      "HalkBank",
+		logo:      `HALK\s*BANK`,
+		legalName: `T[ÜU]RK[İIıi]YE\s*HALK\s*BANKASI`,
+		domain:    "halkbank.com.tr",
+		swift:     "TRHBTR2A",
+	}
+	isBankasiProfile = bankProfile{
+		name:      "Türkiye İş Bankası",
+		logo:      `[İIıi][ŞS]\s*BANKASI|[İIıi][ŞS]BANK`,
+		legalName: `T[ÜU]RK[İIıi]YE\s*[İIıi][ŞS]\s*BANKASI\s*A\.?\s*[ŞS]`,
+		domain:    "isbank.com.tr",
+		swift:     "ISBKTRIS",
+		taxIDs:    []string{"4810058590"},
+	}
+	garantiProfile = bankProfile{
+		name:      "Garanti BBVA",
+		logo:      `GARANT[İIıi]`,
+		legalName: `T[ÜU]RK[İIıi]YE\s*GARANT[İIıi]\s*BANKASI`,
+		domain:    "garantibbva.com.tr",
+		swift:     "TGBATRIS",
+		taxIDs:    []string{"8790017566"},
+	}
+	akbankProfile = bankProfile{
+		name:      "Akbank",
+		logo:      `AKBANK`,
+		legalName: `AKBANK\s*T\.?\s*A\.?\s*[ŞS]`,
+		domain:    "akbank.com",
+		swift:     "AKBKTRIS",
+		taxIDs:    []string{"0150015264"},
+	}
+	ziraatProfile = bankProfile{
+		name:      "Ziraat Bankası",
+		logo:      `Z[İIıi]RAAT\s*BANK`,
+		legalName: `T\.?\s*C\.?\s*Z[İIıi]RAAT\s*BANKASI`,
+		domain:    "ziraatbank.com.tr",
+		swift:     "TCZBTR2A",
+	}
+)
+
+// needsReview reports whether the bank identification of the receipt is
+// below the configured confidence threshold (in percent)
+func needsReview(receipt *Receipt, thresholdPercent int) bool {
+	return receipt.Bank == "" || math.Round(receipt.BankConfidence*100) < float64(thresholdPercent)
+}
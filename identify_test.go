@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIdentifyBank(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		bank       string
+		confidence float64
+	}{
+		{
+			name:       "logo text only",
+			input:      "AKBANK\nALICI: Test User",
+			bank:       "Akbank",
+			confidence: 0.5,
+		},
+		{
+			name:       "SWIFT code with branch suffix",
+			input:      "BIC: TCZBTR2AXXX\nALICI: Test User",
+			bank:       "Ziraat Bankası",
+			confidence: 0.4,
+		},
+		{
+			name:       "MERSİS number in footer",
+			input:      "ALICI: Test User\nMersis No: 0879001756600379",
+			bank:       "Garanti BBVA",
+			confidence: 0.4,
+		},
+		{
+			name:       "logo, footer and tax number",
+			input:      "İşbank\nTÜRKİYE İŞ BANKASI A.Ş. www.isbank.com.tr VKN: 4810058590",
+			bank:       "Türkiye İş Bankası",
+			confidence: 1,
+		},
+		{
+			name:       "issuer footer outweighs counterparty logo",
+			input:      "VAKIFBANK\nALICI BANKA: ZİRAAT BANKASI\nT. VAKIFLAR BANKASI T.A.O. www.vakifbank.com.tr",
+			bank:       "VakıfBank",
+			confidence: 1,
+		},
+		{
+			name:       "counterparty bank registered first",
+			input:      "GARANTİ BBVA\nEFT DEKONTU\nALICI BANKA : TÜRKİYE İŞ BANKASI\nALICI : Test User",
+			bank:       "Garanti BBVA",
+			confidence: 0.5,
+		},
+		{
+			name:       "sender bank of an incoming transfer",
+			input:      "AKBANK\nGELEN EFT DEKONTU\nGÖNDEREN BANKASI : VAKIFBANK\nKARŞI TARAF BANKA : HALKBANK\nALICI : Test User",
+			bank:       "Akbank",
+			confidence: 0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt, _ := defaultRegistry.Parse(tt.input)
+			if receipt.Bank != tt.bank || receipt.BankConfidence != tt.confidence {
+				t.Errorf("Parse() bank = %q (%v), want %q (%v)",
+					receipt.Bank, receipt.BankConfidence, tt.bank, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestFormatBank(t *testing.T) {
+	config := &Configuration{BankConfidenceThreshold: 60}
+
+	tests := []struct {
+		name     string
+		receipt  *Receipt
+		expected string
+	}{
+		{
+			name:     "confident detection",
+			receipt:  &Receipt{Bank: "HalkBank", BankConfidence: 0.9},
+			expected: "**Banka**: HalkBank (%90 güven)",
+		},
+		{
+			name:     "low confidence detection",
+			receipt:  &Receipt{Bank: "HalkBank", BankConfidence: 0.3},
+			expected: "**Banka**: HalkBank (%30 güven) ⚠️ *Lütfen kontrol edin*",
+		},
+		{
+			name:     "no bank detected",
+			receipt:  &Receipt{},
+			expected: "**Banka**: Tespit edilemedi ⚠️",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatBank(tt.receipt, config)
+			if result != tt.expected {
+				t.Errorf("formatBank() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatMessageIncludesBank(t *testing.T) {
+	config := &Configuration{BankConfidenceThreshold: 60, HideCredits: true}
+	receipt := extractReceipt("HALKBANK\nALICI : Test User\nİŞLEM TUTARI (TL) : 100.00")
+
+	result := formatMessage([]*Receipt{receipt}, config)
+	if !strings.HasPrefix(result, "**Banka**: HalkBank (%80 güven)\n**Alıcı**: Test User") {
+		t.Errorf("formatMessage() = %q", result)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// startJobs starts the background jobs of the plugin until stopJobs is
+// called. Every server of a cluster runs them; the jobs take a KV store lock
+// before doing work that must happen once.
+func (p *Plugin) startJobs() {
+	stop := make(chan struct{})
+	p.jobsStop = stop
+	go runEvery(stop, digestCheckInterval, p.postDueDigests)
+	go runEvery(stop, webhookRetryInterval, p.processWebhookQueue)
+}
+
+// stopJobs stops the jobs started by startJobs
+func (p *Plugin) stopJobs() {
+	if p.jobsStop != nil {
+		close(p.jobsStop)
+		p.jobsStop = nil
+	}
+}
+
+// runEvery calls job with the current time at every interval until stop is
+// closed
+func runEvery(stop <-chan struct{}, interval time.Duration, job func(time.Time)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			job(now)
+		}
+	}
+}
+
+// tryLock takes the cluster-wide lock stored under key, which expires after
+// ttl seconds in case its holder stops without releasing it. It returns
+// whether the lock was taken and the function releasing it. Every
+// acquisition stores its own token, so a server whose lock expired does not
+// release the lock another server has taken since.
+func (p *Plugin) tryLock(key string, ttl int64) (bool, func()) {
+	token := []byte(model.NewId())
+	locked, appErr := p.API.KVSetWithOptions(key, token, model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: ttl,
+	})
+	if appErr != nil {
+		p.API.LogError("Failed to acquire lock", "key", key, "error", appErr.Error())
+		return false, nil
+	}
+	if !locked {
+		return false, nil
+	}
+	return true, func() {
+		released, appErr := p.API.KVCompareAndDelete(key, token)
+		if appErr != nil {
+			p.API.LogError("Failed to release lock", "key", key, "error", appErr.Error())
+			return
+		}
+		if !released {
+			p.API.LogWarn("Lock expired before it was released", "key", key)
+		}
+	}
+}
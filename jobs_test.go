@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestTryLock(t *testing.T) {
+	api, kv := newKVTestAPI()
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	locked, unlock := p.tryLock("test_lock", 60)
+	if !locked {
+		t.Fatal("tryLock() = false, want the free lock")
+	}
+	if other, _ := p.tryLock("test_lock", 60); other {
+		t.Error("tryLock() of a held lock = true, want false")
+	}
+	unlock()
+	if kv.get("test_lock") != nil {
+		t.Error("unlock() did not release the lock")
+	}
+
+	// The lock expired and another server took it
+	locked, unlock = p.tryLock("test_lock", 60)
+	if !locked {
+		t.Fatal("tryLock() after release = false, want true")
+	}
+	kv.set("test_lock", []byte("other"))
+	unlock()
+	if string(kv.get("test_lock")) != "other" {
+		t.Error("unlock() released the lock of another server")
+	}
+}
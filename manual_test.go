@@ -17,7 +17,7 @@ func TestManualAmount(t *testing.T) {
 	for i, input := range testCases {
 		fmt.Printf("Test %d:\n", i+1)
 		fmt.Printf("Input: %s\n", input)
-		result := extractFields(input)
+		result := formatReceipt(extractReceipt(input), &Configuration{})
 		fmt.Printf("Output: %s\n\n", result)
 	}
 }
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Locales for rendering amounts, selected by the AmountLocale setting
+const (
+	localeTurkish       = "tr"
+	localeEnglish       = "en"
+	defaultAmountLocale = localeTurkish
+
+	// defaultCurrency is assumed for amounts printed without a currency, as
+	// the supported banks print domestic transfers in lira
+	defaultCurrency = "TRY"
+)
+
+// numberFormat holds the separators of a locale
+type numberFormat struct {
+	thousands string
+	decimal   string
+}
+
+// numberFormats are the amount formats of the supported locales
+var numberFormats = map[string]numberFormat{
+	localeTurkish: {thousands: ".", decimal: ","},
+	localeEnglish: {thousands: ",", decimal: "."},
+}
+
+// currency describes how a currency is printed on receipts and rendered
+type currency struct {
+	code    string
+	markers []string
+	labelTR string
+	labelEN string
+}
+
+// currencies are the currencies found on Turkish bank receipts. Gold
+// accounts are kept in grams under the XAU code.
+var currencies = []currency{
+	{code: "TRY", markers: []string{"TL", "TRY", "₺"}, labelTR: "TL", labelEN: "TRY"},
+	{code: "USD", markers: []string{"USD", "$", "DOLAR", "DOLARI"}, labelTR: "USD", labelEN: "USD"},
+	{code: "EUR", markers: []string{"EUR", "€", "EURO", "AVRO"}, labelTR: "EUR", labelEN: "EUR"},
+	{code: "GBP", markers: []string{"GBP", "£", "STERLİN", "STERLIN"}, labelTR: "GBP", labelEN: "GBP"},
+	{code: "XAU", markers: []string{"XAU", "GR", "GRAM", "ALTIN"}, labelTR: "gr altın", labelEN: "g gold"},
+}
+
+// currencyPattern matches the currency markers of currencies
+const currencyPattern = `(?:₺|\$|€|£|\b(?:TL|TRY|USD|EUR|GBP|XAU|DOLAR[Iı]?|EURO|AVRO|STERL[İIi]N|GR(?:AM)?|ALTIN)\b)`
+
+var (
+	// reAmountNoise matches everything but digits and separators
+	reAmountNoise = regexp.MustCompile(`[^0-9.,]`)
+	// reCurrencyMarker matches the currency markers printed next to amounts
+	reCurrencyMarker = regexp.MustCompile(`(?i)` + currencyPattern)
+)
+
+// parseAmount converts a printed amount into minor units without going
+// through floating point. Turkish ("1.234,56") and international
+// ("1,234.56") formats are told apart by the last separator: with both
+// separators present it is the decimal point, a lone separator followed by
+// one or two digits is a decimal point and one followed by three digits
+// groups thousands. Malformed groups and more than two fraction digits are
+// rejected.
+func parseAmount(text string) (Amount, bool) {
+	value, ok := parseDecimal(text, 2, false)
+	return Amount(value), ok
+}
+
+// parseMoney converts a printed amount of currency into minor units, reading
+// gold amounts in grams
+func parseMoney(text, currency string) (Amount, bool) {
+	if currency == "XAU" {
+		return parseGrams(text)
+	}
+	return parseAmount(text)
+}
+
+// parseGrams converts a printed gold amount such as "2,500" into hundredths
+// of a gram. Gold is printed with up to three fraction digits, so the last
+// separator is always the decimal point. Amounts whose third fraction digit
+// is not zero cannot be kept in hundredths and are rejected.
+func parseGrams(text string) (Amount, bool) {
+	value, ok := parseDecimal(text, 3, true)
+	if !ok || value%10 != 0 {
+		return 0, false
+	}
+	return Amount(value / 10), true
+}
+
+// parseRate converts a printed exchange rate such as "32,5000" into a Rate.
+// Rates always have a fraction, so the last separator is the decimal point
+// even when three digits follow it, as in "38,125".
+func parseRate(text string) (Rate, bool) {
+	value, ok := parseDecimal(text, 6, true)
+	return Rate(value), ok
+}
+
+// parseDecimal converts a printed number into an integer scaled by
+// 10^fractionDigits, rejecting numbers with more fraction digits. With
+// lastIsDecimal the last separator is the decimal point; otherwise a lone
+// separator followed by three digits groups thousands.
+func parseDecimal(text string, fractionDigits int, lastIsDecimal bool) (int64, bool) {
+	text = strings.TrimSpace(text)
+	negative := strings.HasPrefix(text, "-") || strings.HasPrefix(text, "−")
+	digits := reAmountNoise.ReplaceAllString(text, "")
+	if strings.Trim(digits, ".,") == "" {
+		return 0, false
+	}
+
+	whole, fraction, ok := splitDecimal(digits, fractionDigits, lastIsDecimal)
+	if !ok {
+		return 0, false
+	}
+	whole, ok = ungroup(whole)
+	if !ok {
+		return 0, false
+	}
+	for len(fraction) < fractionDigits {
+		fraction += "0"
+	}
+
+	value, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		value = -value
+	}
+	return value, true
+}
+
+// splitDecimal splits digits at its decimal separator, if it has one
+func splitDecimal(digits string, fractionDigits int, lastIsDecimal bool) (whole, fraction string, ok bool) {
+	lastDot, lastComma := strings.LastIndex(digits, "."), strings.LastIndex(digits, ",")
+
+	decimal := -1
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimal = lastDot
+		if lastComma > lastDot {
+			decimal = lastComma
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		last := lastDot
+		if lastComma >= 0 {
+			last = lastComma
+		}
+		if lastIsDecimal || (strings.Count(digits, digits[last:last+1]) == 1 && len(digits)-last-1 != 3) {
+			decimal = last
+		}
+	}
+
+	if decimal < 0 {
+		return digits, "", true
+	}
+	fraction = digits[decimal+1:]
+	if len(fraction) > fractionDigits || strings.ContainsAny(fraction, ".,") {
+		return "", "", false
+	}
+	return digits[:decimal], fraction, true
+}
+
+// ungroup removes the thousands separators from the whole part, checking
+// that every group after the first has three digits
+func ungroup(whole string) (string, bool) {
+	if whole == "" {
+		return "0", true
+	}
+	if strings.Contains(whole, ".") && strings.Contains(whole, ",") {
+		return "", false
+	}
+
+	groups := strings.FieldsFunc(whole, func(r rune) bool { return r == '.' || r == ',' })
+	if len(groups) == 1 {
+		return groups[0], whole == groups[0]
+	}
+	if len(groups[0]) > 3 {
+		return "", false
+	}
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return "", false
+		}
+	}
+	return strings.Join(groups, ""), strings.Count(whole, ".")+strings.Count(whole, ",") == len(groups)-1
+}
+
+// detectCurrency returns the ISO code of the currency printed in text, or an
+// empty string when no currency is printed. The marker closest to the end
+// wins, so the currency printed next to a number overrides one in its label.
+func detectCurrency(text string) string {
+	markers := reCurrencyMarker.FindAllString(text, -1)
+	if len(markers) == 0 {
+		return ""
+	}
+	marker := strings.ToUpperSpecial(unicode.TurkishCase, markers[len(markers)-1])
+	for _, currency := range currencies {
+		for _, candidate := range currency.markers {
+			if marker == candidate {
+				return currency.code
+			}
+		}
+	}
+	return ""
+}
+
+// Format renders the amount with the thousands and decimal separators of the
+// locale, falling back to the default locale
+func (a Amount) Format(locale string) string {
+	return formatDecimal(int64(a), 2, locale)
+}
+
+// Format renders the rate with four fraction digits in the locale
+func (r Rate) Format(locale string) string {
+	return formatDecimal((int64(r)+50)/100, 4, locale)
+}
+
+// formatDecimal renders an integer scaled by 10^fractionDigits with the
+// separators of the locale
+func formatDecimal(value int64, fractionDigits int, locale string) string {
+	format, ok := numberFormats[locale]
+	if !ok {
+		format = numberFormats[defaultAmountLocale]
+	}
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	scale := int64(1)
+	for i := 0; i < fractionDigits; i++ {
+		scale *= 10
+	}
+
+	whole := strconv.FormatInt(value/scale, 10)
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(format.thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return fmt.Sprintf("%s%s%s%0*d", sign, grouped.String(), format.decimal, fractionDigits, value%scale)
+}
+
+// formatMoney renders the amount with its currency in the locale. Without a
+// currency only the number is rendered.
+func formatMoney(amount Amount, currency, locale string) string {
+	number := amount.Format(locale)
+	if label := currencyLabel(currency, locale); label != "" {
+		return number + " " + label
+	}
+	return number
+}
+
+// currencyLabel returns how a currency is written after amounts in the
+// locale. Unknown currencies are written by their code.
+func currencyLabel(code, locale string) string {
+	for _, currency := range currencies {
+		if currency.code != code {
+			continue
+		}
+		if locale == localeEnglish {
+			return currency.labelEN
+		}
+		return currency.labelTR
+	}
+	return code
+}
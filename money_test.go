@@ -0,0 +1,215 @@
+package main
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Amount
+		ok       bool
+	}{
+		{
+			name:     "international format",
+			input:    "1,500.00",
+			expected: 150000,
+			ok:       true,
+		},
+		{
+			name:     "Turkish format",
+			input:    "1.234.567,89",
+			expected: 123456789,
+			ok:       true,
+		},
+		{
+			name:     "thousands separator only",
+			input:    "1,500",
+			expected: 150000,
+			ok:       true,
+		},
+		{
+			name:     "Turkish thousands separator only",
+			input:    "1.500",
+			expected: 150000,
+			ok:       true,
+		},
+		{
+			name:     "repeated thousands separators",
+			input:    "1,234,567",
+			expected: 123456700,
+			ok:       true,
+		},
+		{
+			name:     "single fraction digit",
+			input:    "12.5",
+			expected: 1250,
+			ok:       true,
+		},
+		{
+			name:     "Turkish decimal comma",
+			input:    "750,25",
+			expected: 75025,
+			ok:       true,
+		},
+		{
+			name:     "currency and spaces",
+			input:    "₺ 1.500,00 TL",
+			expected: 150000,
+			ok:       true,
+		},
+		{
+			name:     "large amount without float rounding",
+			input:    "92.233.720.368.547,75",
+			expected: 9223372036854775,
+			ok:       true,
+		},
+		{
+			name:     "negative amount",
+			input:    "-250.75",
+			expected: -25075,
+			ok:       true,
+		},
+		{
+			name:  "malformed thousands groups",
+			input: "1.23.45",
+		},
+		{
+			name:  "more than two fraction digits",
+			input: "12,3456",
+		},
+		{
+			name:  "mixed separators in the whole part",
+			input: "1.234,567.89",
+		},
+		{
+			name:  "separators only",
+			input: ".,",
+		},
+		{
+			name:  "empty input",
+			input: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := parseAmount(tt.input)
+			if result != tt.expected || ok != tt.ok {
+				t.Errorf("parseAmount() = %v, %v, want %v, %v", result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestDetectCurrency(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "İŞLEM TUTARI (TL) : 100,00", expected: "TRY"},
+		{input: "TUTAR: 100,00 ₺", expected: "TRY"},
+		{input: "TUTAR: TRY 100.00", expected: "TRY"},
+		{input: "DÖVİZ TUTARI: 1.000,00 USD", expected: "USD"},
+		{input: "TUTAR: $1,000.00", expected: "USD"},
+		{input: "TUTAR: 250,00 ABD Doları", expected: "USD"},
+		{input: "TUTAR: €500", expected: "EUR"},
+		{input: "TUTAR: 500 Avro", expected: "EUR"},
+		{input: "TUTAR: £75.50", expected: "GBP"},
+		{input: "TUTAR: 75,50 sterlin", expected: "GBP"},
+		{input: "ALTIN MİKTARI: 10,50 GR", expected: "XAU"},
+		{input: "İŞLEM TUTARI (TL) : 100,00 USD", expected: "USD"},
+		{input: "TUTAR: 100.00", expected: ""},
+		{input: "TUTAR: 100.00 TLX", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := detectCurrency(tt.input); result != tt.expected {
+				t.Errorf("detectCurrency(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   Amount
+		currency string
+		locale   string
+		expected string
+	}{
+		{name: "Turkish lira", amount: 123456789, currency: "TRY", locale: localeTurkish, expected: "1.234.567,89 TL"},
+		{name: "English lira", amount: 123456789, currency: "TRY", locale: localeEnglish, expected: "1,234,567.89 TRY"},
+		{name: "unknown locale falls back to Turkish", amount: 150000, currency: "TRY", locale: "xx", expected: "1.500,00 TL"},
+		{name: "small amount", amount: 5, currency: "TRY", locale: localeTurkish, expected: "0,05 TL"},
+		{name: "three digit whole part", amount: 99999, currency: "TRY", locale: localeTurkish, expected: "999,99 TL"},
+		{name: "negative amount", amount: -150050, currency: "TRY", locale: localeEnglish, expected: "-1,500.50 TRY"},
+		{name: "no currency", amount: 150000, locale: localeTurkish, expected: "1.500,00"},
+		{name: "foreign currency", amount: 100000, currency: "USD", locale: localeTurkish, expected: "1.000,00 USD"},
+		{name: "gram gold in Turkish", amount: 1050, currency: "XAU", locale: localeTurkish, expected: "10,50 gr altın"},
+		{name: "gram gold in English", amount: 1050, currency: "XAU", locale: localeEnglish, expected: "10.50 g gold"},
+		{name: "unknown currency code", amount: 100, currency: "CHF", locale: localeEnglish, expected: "1.00 CHF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := formatMoney(tt.amount, tt.currency, tt.locale); result != tt.expected {
+				t.Errorf("formatMoney() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseGrams(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Amount
+		ok       bool
+	}{
+		{input: "10,50", expected: 1050, ok: true},
+		{input: "2,500", expected: 250, ok: true},
+		{input: "1.250,000", expected: 125000, ok: true},
+		{input: "25", expected: 2500, ok: true},
+		{input: "2,125", ok: false},
+		{input: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, ok := parseGrams(tt.input)
+			if result != tt.expected || ok != tt.ok {
+				t.Errorf("parseGrams() = %v, %v, want %v, %v", result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Rate
+		ok       bool
+	}{
+		{input: "32,5000", expected: 32500000, ok: true},
+		{input: "32.4567", expected: 32456700, ok: true},
+		{input: "1.234,567891", expected: 1234567891, ok: true},
+		{input: "38,125", expected: 38125000, ok: true},
+		{input: "34.125", expected: 34125000, ok: true},
+		{input: "1,0851234567", ok: false},
+		{input: "", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, ok := parseRate(tt.input)
+			if result != tt.expected || ok != tt.ok {
+				t.Errorf("parseRate() = %v, %v, want %v, %v", result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+
+	if result := Rate(32456789).Format(localeTurkish); result != "32,4568" {
+		t.Errorf("Rate.Format() = %q, want %q", result, "32,4568")
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Output modes for parsed receipts. The user's original message is kept in
+// every mode.
+const (
+	// outputModeAppend appends the rendered receipts below the original message
+	outputModeAppend = "append"
+	// outputModeReply posts the rendered receipts as a threaded reply from
+	// the plugin bot
+	outputModeReply = "reply"
+	// outputModeProps only stores the receipts in the post props and sets a
+	// custom post type for the webapp to render
+	outputModeProps = "props"
+)
+
+// receiptPostType is the custom post type used by outputModeProps
+const receiptPostType = "custom_dekont_receipt"
+
+// originalMessageProp keeps the user's message of a post the receipts were
+// appended to, so that reprocessing replaces the rendering instead of
+// appending another one
+const originalMessageProp = "dekont_original_message"
+
+// publishReceipts delivers the receipts parsed from the attachments of post
+// according to the configured output mode
+func (p *Plugin) publishReceipts(post *model.Post, results []*attachmentResult, config *Configuration) error {
+	receipts := attachmentReceipts(results)
+	switch config.OutputMode {
+	case outputModeReply:
+		reply := &model.Post{
+			ChannelId: post.ChannelId,
+			RootId:    threadRootID(post),
+			UserId:    p.botUserID,
+			Message:   formatAttachments(results, config),
+		}
+		setReceiptProps(reply, receipts, config)
+		if _, appErr := p.API.CreatePost(reply); appErr != nil {
+			return appErr
+		}
+
+	case outputModeProps:
+		post.Type = receiptPostType
+		post.AddProp("dekont_receipts", propReceipts(receipts, config))
+		setReceiptProps(post, receipts, config)
+		if _, appErr := p.API.UpdatePost(post); appErr != nil {
+			return appErr
+		}
+
+	default:
+		original, ok := post.GetProp(originalMessageProp).(string)
+		if !ok {
+			original = post.Message
+			post.AddProp(originalMessageProp, original)
+		}
+		post.Message = appendMessage(original, formatAttachments(results, config))
+		setReceiptProps(post, receipts, config)
+		if _, appErr := p.API.UpdatePost(post); appErr != nil {
+			return appErr
+		}
+	}
+
+	return nil
+}
+
+// threadRootID returns the root of the thread post belongs to
+func threadRootID(post *model.Post) string {
+	if post.RootId != "" {
+		return post.RootId
+	}
+	return post.Id
+}
+
+// appendMessage adds rendered receipts below the original message
+func appendMessage(original, rendered string) string {
+	if original == "" {
+		return rendered
+	}
+	return original + "\n\n" + rendered
+}
+
+// propReceipts returns the receipts stored in the post props, which every
+// channel member can read. Their IBANs are masked unless ShowFullIBAN is set.
+func propReceipts(receipts []*Receipt, config *Configuration) []*Receipt {
+	if config.ShowFullIBAN {
+		return receipts
+	}
+	masked := make([]*Receipt, len(receipts))
+	for i, receipt := range receipts {
+		copied := *receipt
+		copied.SenderIBAN = formatIBAN(receipt.SenderIBAN, false)
+		copied.RecipientIBAN = formatIBAN(receipt.RecipientIBAN, false)
+		masked[i] = &copied
+	}
+	return masked
+}
+
+// setReceiptProps stores the bank identification in the post props. With
+// several receipts the post needs review if any of them does.
+func setReceiptProps(post *model.Post, receipts []*Receipt, config *Configuration) {
+	review := false
+	for _, receipt := range receipts {
+		review = review || needsReview(receipt, config.BankConfidenceThreshold)
+	}
+
+	post.AddProp("dekont_bank", receipts[0].Bank)
+	post.AddProp("dekont_bank_confidence", receipts[0].BankConfidence)
+	post.AddProp("dekont_needs_review", review)
+	post.AddProp("dekont_receipt_count", len(receipts))
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPublishReceipts(t *testing.T) {
+	results := []*attachmentResult{{
+		fileInfo: &model.FileInfo{Name: "dekont.pdf"},
+		receipts: []*Receipt{{Bank: "HalkBank", BankConfidence: 0.8, Recipient: "Test User", AmountText: "100.00"}},
+	}}
+
+	t.Run("append keeps the original message", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		post := &model.Post{Id: "post", Message: "Kira dekontu ektedir"}
+		config := &Configuration{OutputMode: outputModeAppend, BankConfidenceThreshold: 60, HideCredits: true}
+		if err := p.publishReceipts(post, results, config); err != nil {
+			t.Fatalf("publishReceipts() error = %v", err)
+		}
+
+		if !strings.HasPrefix(post.Message, "Kira dekontu ektedir\n\n**Banka**: HalkBank") {
+			t.Errorf("post.Message = %q", post.Message)
+		}
+		api.AssertExpectations(t)
+	})
+
+	t.Run("reply posts in the thread as the bot", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("CreatePost", mock.MatchedBy(func(reply *model.Post) bool {
+			return reply.RootId == "root" && reply.ChannelId == "channel" && reply.UserId == "bot" &&
+				strings.Contains(reply.Message, "**Alıcı**: Test User")
+		})).Return(nil, nil)
+		p := &Plugin{botUserID: "bot"}
+		p.SetAPI(api)
+
+		post := &model.Post{Id: "post", RootId: "root", ChannelId: "channel", Message: "original"}
+		config := &Configuration{OutputMode: outputModeReply, BankConfidenceThreshold: 60}
+		if err := p.publishReceipts(post, results, config); err != nil {
+			t.Fatalf("publishReceipts() error = %v", err)
+		}
+
+		if post.Message != "original" {
+			t.Errorf("post.Message = %q, want original message", post.Message)
+		}
+		api.AssertExpectations(t)
+	})
+
+	t.Run("props sets the custom post type", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		post := &model.Post{Id: "post", Message: "original"}
+		config := &Configuration{OutputMode: outputModeProps, BankConfidenceThreshold: 60}
+		if err := p.publishReceipts(post, results, config); err != nil {
+			t.Fatalf("publishReceipts() error = %v", err)
+		}
+
+		if post.Message != "original" || post.Type != receiptPostType {
+			t.Errorf("post = %q (%s), want original message with type %s", post.Message, post.Type, receiptPostType)
+		}
+		if post.GetProp("dekont_receipts") == nil {
+			t.Error("post props missing dekont_receipts")
+		}
+		api.AssertExpectations(t)
+	})
+
+	t.Run("props masks IBANs", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(nil, nil)
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		iban := "TR330006100519786457841326"
+		receipt := &Receipt{Bank: "HalkBank", BankConfidence: 0.8, SenderIBAN: iban, RecipientIBAN: iban}
+		ibanResults := []*attachmentResult{{fileInfo: &model.FileInfo{Name: "dekont.pdf"}, receipts: []*Receipt{receipt}}}
+
+		for _, full := range []bool{false, true} {
+			post := &model.Post{Id: "post"}
+			config := &Configuration{OutputMode: outputModeProps, BankConfidenceThreshold: 60, ShowFullIBAN: full}
+			if err := p.publishReceipts(post, ibanResults, config); err != nil {
+				t.Fatalf("publishReceipts() error = %v", err)
+			}
+
+			expected := "TR33 **** **** 1326"
+			if full {
+				expected = iban
+			}
+			stored := post.GetProp("dekont_receipts").([]*Receipt)
+			if stored[0].SenderIBAN != expected || stored[0].RecipientIBAN != expected {
+				t.Errorf("ShowFullIBAN=%v: stored IBANs = %q, %q, want %q", full, stored[0].SenderIBAN, stored[0].RecipientIBAN, expected)
+			}
+		}
+		if receipt.SenderIBAN != iban {
+			t.Errorf("publishReceipts() changed the parsed receipt IBAN to %q", receipt.SenderIBAN)
+		}
+	})
+}
+
+func TestFormatAttachments(t *testing.T) {
+	config := &Configuration{BankConfidenceThreshold: 60, HideCredits: true}
+	rent := &Receipt{Bank: "HalkBank", BankConfidence: 0.8, Recipient: "Ev Sahibi", Amount: 150000, AmountText: "1500.00", Currency: "TRY"}
+	dues := &Receipt{Bank: "Akbank", BankConfidence: 0.8, Recipient: "Site Yönetimi", Amount: 45050, AmountText: "450.50", Currency: "TRY"}
+	invoice := &Receipt{Bank: "Garanti BBVA", BankConfidence: 0.8, Recipient: "Northwind", Amount: 10000, AmountText: "100.00", Currency: "USD"}
+	file := func(name string) *model.FileInfo { return &model.FileInfo{Name: name} }
+
+	t.Run("several files", func(t *testing.T) {
+		message := formatAttachments([]*attachmentResult{
+			{fileInfo: file("kira.pdf"), receipts: []*Receipt{rent}},
+			{fileInfo: file("bozuk.pdf"), err: errors.New("malformed PDF")},
+			{fileInfo: file("aidat|fatura.pdf"), receipts: []*Receipt{dues, invoice}},
+			{fileInfo: file("fatura.pdf")},
+			{fileInfo: file("eski.pdf"), duplicates: 1},
+		}, config)
+
+		for _, expected := range []string{
+			"**2 dosyada 3 dekont bulundu**\n\n| # | Dosya | Banka |",
+			"| 1 | kira.pdf | HalkBank |",
+			"| 2 | aidat\\|fatura.pdf | Akbank |",
+			"| 3 | aidat\\|fatura.pdf | Garanti BBVA |",
+			"\n\n**Genel Toplam**: 1.950,50 TL + 100,00 USD",
+			"\n\n**İşlenemeyen dosyalar**\n- bozuk.pdf: dosya okunamadı\n- fatura.pdf: dekont bulunamadı\n- eski.pdf: dekontlar daha önce paylaşılmış",
+		} {
+			if !strings.Contains(message, expected) {
+				t.Errorf("formatAttachments() = %q, want it to contain %q", message, expected)
+			}
+		}
+	})
+
+	t.Run("one file with receipts", func(t *testing.T) {
+		message := formatAttachments([]*attachmentResult{
+			{fileInfo: file("kira.pdf"), receipts: []*Receipt{rent}},
+			{fileInfo: file("bozuk.pdf"), err: errors.New("malformed PDF")},
+		}, config)
+
+		expected := formatMessage([]*Receipt{rent}, config) + "\n\n**İşlenemeyen dosyalar**\n- bozuk.pdf: dosya okunamadı"
+		if message != expected {
+			t.Errorf("formatAttachments() = %q, want %q", message, expected)
+		}
+	})
+}
+
+func TestMessageHasBeenPostedAggregatesAttachments(t *testing.T) {
+	files := map[string][]byte{
+		"rent": buildTestPDF([]string{"HALKBANK", "ALICI : Ev Sahibi", "ISLEM TUTARI (TL) : 1.500,00"}),
+		"dues": buildTestPDF([]string{"HALKBANK", "ALICI : Site Yonetimi", "ISLEM TUTARI (TL) : 450,50"}),
+		"bad":  []byte("%PDF-1.4 truncated"),
+	}
+
+	api, _ := newKVTestAPI()
+	for id, data := range files {
+		api.On("GetFileInfo", id).Return(&model.FileInfo{Id: id, Name: id + ".pdf", Size: int64(len(data))}, nil)
+		api.On("GetFile", id).Return(data, nil)
+	}
+	api.On("GetFileInfo", "photo").Return(&model.FileInfo{Id: "photo", Name: "photo.png"}, nil)
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return strings.Contains(post.Message, "**2 dosyada 2 dekont bulundu**") &&
+			strings.Contains(post.Message, "| 1 | rent.pdf | HalkBank |") &&
+			strings.Contains(post.Message, "| 2 | dues.pdf | HalkBank |") &&
+			strings.Contains(post.Message, "**Genel Toplam**: 1.950,50 TL") &&
+			strings.Contains(post.Message, "- bad.pdf: dosya okunamadı") &&
+			!strings.Contains(post.Message, "photo")
+	})).Return(nil, nil).Once()
+
+	p := &Plugin{botUserID: "bot", configuration: &Configuration{
+		EnablePlugin: true, OutputMode: outputModeAppend, MaxFileSizeMB: 10, BankConfidenceThreshold: 60, HideCredits: true,
+		NotifyOnProcessingError: true,
+	}}
+	p.SetAPI(api)
+	p.store = newTransactionStore(api)
+
+	post := &model.Post{Id: "post", ChannelId: "channel", UserId: "user", FileIds: []string{"rent", "photo", "bad", "dues"}}
+	p.MessageHasBeenPosted(nil, post)
+
+	api.AssertExpectations(t)
+	for _, id := range []string{"rent", "dues"} {
+		if tx, err := p.store.Get(transactionID(id, 0)); err != nil || tx == nil || tx.PostID != "post" {
+			t.Errorf("store.Get(%s) = %+v, %v, want the stored transaction", id, tx, err)
+		}
+	}
+}
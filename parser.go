@@ -0,0 +1,270 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// minDetectScore is the lowest detection score a bank parser needs to be
+// selected. Below it the generic patterns are used instead.
+const minDetectScore = 0.3
+
+// BankParser recognizes and parses the receipts of a single bank.
+type BankParser interface {
+	// Name returns the display name of the bank.
+	Name() string
+	// Detect returns a score between 0 and 1 telling how likely it is that
+	// text was issued by this bank.
+	Detect(text string) float64
+	// Parse extracts the transaction details from text.
+	Parse(text string) *Receipt
+}
+
+// detectSignal is a piece of evidence pointing to a bank, such as its name
+// or a label only its receipts use.
+type detectSignal struct {
+	re     *regexp.Regexp
+	weight float64
+}
+
+// fieldPatterns lists the regexes tried, in order, for every field. The
+// first capture group of the first matching regex is used.
+type fieldPatterns struct {
+	recipient   []*regexp.Regexp
+	sender      []*regexp.Regexp
+	description []*regexp.Regexp
+	amount      []*regexp.Regexp
+	date        []*regexp.Regexp
+	reference   []*regexp.Regexp
+}
+
+// patternParser is a regex driven BankParser. Every supported bank is a
+// patternParser with its own signals and field patterns.
+type patternParser struct {
+	name     string
+	signals  []detectSignal
+	patterns fieldPatterns
+	// generic is set for parsers reading with the generic patterns, whose
+	// matches are less certain than those of bank specific patterns
+	generic bool
+}
+
+// Name returns the display name of the bank
+func (pp *patternParser) Name() string {
+	return pp.name
+}
+
+// Detect sums the weights of all matching signals, rounded to two decimals
+// and capped at 1
+func (pp *patternParser) Detect(text string) float64 {
+	score := 0.0
+	for _, signal := range pp.signals {
+		if signal.re.MatchString(text) {
+			score += signal.weight
+		}
+	}
+	if score > 1 {
+		score = 1
+	}
+	return math.Round(score*100) / 100
+}
+
+// Parse extracts the transaction details using only this parser's patterns,
+// falling back to the shared patterns for the fields common to all banks
+func (pp *patternParser) Parse(text string) *Receipt {
+	receipt := &Receipt{}
+	own := fieldMatcher{receipt: receipt, owner: pp.name, base: confidenceHigh}
+	if pp.generic {
+		own.base = confidenceMedium
+	}
+	shared := fieldMatcher{receipt: receipt, owner: "shared", base: confidenceMedium}
+
+	receipt.Recipient = own.party(fieldRecipient, pp.patterns.recipient, text)
+	receipt.Sender = own.party(fieldSender, pp.patterns.sender, text)
+	receipt.Description = own.text(fieldDescription, pp.patterns.description, text)
+	receipt.DateText = own.text(fieldDate, pp.patterns.date, text)
+
+	chargeFields := []string{fieldFee, fieldCommission, fieldTax, fieldTotal}
+	for i, charge := range []*Amount{&receipt.Fee, &receipt.Commission, &receipt.Tax, &receipt.Total} {
+		var currency string
+		*charge, currency = shared.amount(chargeFields[i], chargePatterns[i], text)
+		if receipt.ChargeCurrency == "" {
+			receipt.ChargeCurrency = currency
+		}
+	}
+
+	m := own.submatch(fieldAmount, pp.patterns.amount, maskCharges(text))
+	if m == nil {
+		// Only a labelled charge, usually the total, carries an amount
+		if m = own.submatch(fieldAmount, pp.patterns.amount, text); m != nil {
+			receipt.setSource(fieldAmount, FieldSource{Rule: receipt.Sources[fieldAmount].Rule, Confidence: confidenceLow})
+		}
+	}
+	if m != nil {
+		receipt.AmountText = strings.TrimSpace(m[1])
+		currency := detectCurrency(m[0])
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		if amount, ok := parseMoney(receipt.AmountText, currency); ok {
+			receipt.Amount, receipt.Currency = amount, currency
+		}
+	}
+	if receipt.Currency != defaultCurrency {
+		receipt.LocalAmount, _ = shared.amount(fieldLocalAmount, localAmountPatterns, text)
+		if m := shared.submatch(fieldExchangeRate, exchangeRatePatterns, text); m != nil {
+			if rate, ok := parseRate(m[1]); ok {
+				receipt.ExchangeRate = rate
+			} else {
+				delete(receipt.Sources, fieldExchangeRate)
+			}
+		}
+	}
+	if receipt.ChargeCurrency == receipt.Currency {
+		receipt.ChargeCurrency = ""
+	}
+	receipt.TotalMismatch = totalMismatch(receipt)
+
+	sender, recipient := extractIBANs(text)
+	receipt.SenderIBAN, receipt.RecipientIBAN = sender.iban, recipient.iban
+	for field, match := range map[string]ibanMatch{fieldSenderIBAN: sender, fieldRecipientIBAN: recipient} {
+		if match.iban != "" {
+			receipt.setSource(field, match.source())
+		}
+	}
+
+	if receipt.DateText == "" {
+		receipt.DateText = shared.text(fieldDate, datePatterns, text)
+	}
+	receipt.Date = parseDate(receipt.DateText)
+
+	m = own.submatch(fieldReference, pp.patterns.reference, text)
+	if m == nil {
+		m = shared.submatch(fieldReference, referencePatterns, text)
+	}
+	if m != nil {
+		receipt.ReferenceNumber = normalizeReference(m[1])
+	}
+	return receipt
+}
+
+// chargePatterns are the fee, commission, tax and total patterns, in the
+// order of the Receipt fields they fill
+var chargePatterns = [][]*regexp.Regexp{feePatterns, commissionPatterns, taxPatterns, totalPatterns}
+
+// maskCharges removes the labelled fees, taxes and totals from text so the
+// amount patterns cannot pick them up as the transferred amount
+func maskCharges(text string) string {
+	for _, patterns := range chargePatterns {
+		for _, re := range patterns {
+			text = re.ReplaceAllString(text, "")
+		}
+	}
+	return text
+}
+
+// totalMismatch reports whether the printed total differs from the amount
+// plus its fees and taxes. Receipts charging fees in another currency than
+// the amount, usually lira fees on foreign transfers, are not checked.
+func totalMismatch(receipt *Receipt) bool {
+	if receipt.Total == 0 || receipt.Amount == 0 || receipt.ChargeCurrency != "" {
+		return false
+	}
+	return receipt.Amount+receipt.Fee+receipt.Commission+receipt.Tax != receipt.Total
+}
+
+// normalizeReference upper-cases a reference number and drops its spacing so
+// references typed by users match the printed ones
+func normalizeReference(reference string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(reference), ""))
+}
+
+// parserRegistry holds the bank parsers and the generic fallback parser
+type parserRegistry struct {
+	parsers  []BankParser
+	fallback BankParser
+}
+
+// newParserRegistry creates a registry that falls back to the given parser
+func newParserRegistry(fallback BankParser) *parserRegistry {
+	return &parserRegistry{fallback: fallback}
+}
+
+// Register adds a bank parser. On equal detection scores the parser
+// registered first wins.
+func (r *parserRegistry) Register(parser BankParser) {
+	r.parsers = append(r.parsers, parser)
+}
+
+// reCounterpartyBank matches the lines naming the bank of a party of the
+// transfer, such as "ALICI BANKA : ZİRAAT BANKASI". The banks named there
+// are often not the issuing bank.
+var reCounterpartyBank = regexp.MustCompile(`(?im)^.*\b(?:AL[Iı]C[Iı]|G[ÖO]NDEREN|KAR[ŞS][Iı](?:\s*TARAF)?)\s*BANKA.*$`)
+
+// Detect returns the bank parser with the highest score, or nil when no
+// parser reaches minDetectScore. Counterparty bank lines are left out, so
+// their bank names do not count as signals of the issuing bank.
+func (r *parserRegistry) Detect(text string) (BankParser, float64) {
+	text = reCounterpartyBank.ReplaceAllString(text, "")
+	var best BankParser
+	bestScore := 0.0
+	for _, parser := range r.parsers {
+		if score := parser.Detect(text); score > bestScore {
+			best, bestScore = parser, score
+		}
+	}
+	if bestScore < minDetectScore {
+		return nil, bestScore
+	}
+	return best, bestScore
+}
+
+// Parse identifies the issuing bank and runs only that bank's parser, then
+// classifies the transaction. The generic fallback parser is used when no
+// bank wins, leaving Bank empty.
+func (r *parserRegistry) Parse(text string) (*Receipt, BankParser) {
+	parser, score := r.Detect(text)
+	if parser == nil {
+		receipt := r.fallback.Parse(text)
+		receipt.Type = classifyTransaction(text, receipt)
+		return receipt, nil
+	}
+	receipt := parser.Parse(text)
+	receipt.Bank = parser.Name()
+	receipt.BankConfidence = score
+	receipt.Type = classifyTransaction(text, receipt)
+	return receipt, parser
+}
+
+// defaultRegistry contains the built-in bank parsers
+var defaultRegistry = newDefaultRegistry()
+
+// newDefaultRegistry registers the built-in bank parsers in priority order
+func newDefaultRegistry() *parserRegistry {
+	registry := newParserRegistry(newGenericParser())
+	registry.Register(newVakifBankParser())
+	registry.Register(newYapiKrediParser())
+	registry.Register(newKuveytTurkParser())
+	registry.Register(newHalkBankParser())
+	registry.Register(newProfileParser(isBankasiProfile))
+	registry.Register(newProfileParser(garantiProfile))
+	registry.Register(newProfileParser(akbankProfile))
+	registry.Register(newProfileParser(ziraatProfile))
+	return registry
+}
+
+// extractReceipt parses the transaction details from PDF text. It returns
+// nil when nothing meaningful was found.
+func (r *parserRegistry) extractReceipt(text string) *Receipt {
+	receipt, _ := r.Parse(text)
+	if receipt.IsEmpty() {
+		return nil
+	}
+	return receipt
+}
+
+// extractReceipt parses PDF text with the built-in bank parsers
+func extractReceipt(text string) *Receipt {
+	return defaultRegistry.extractReceipt(text)
+}
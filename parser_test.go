@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestParserRegistryDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "VakıfBank by name",
+			input:    "VAKIFBANK EFT DEKONTU\nALICI: Test User",
+			expected: "VakıfBank",
+		},
+		{
+			name:     "Yapı Kredi by labels",
+			input:    "ALICI ADI: Test User\nGİDEN EFT TUTARI: 100.00",
+			expected: "Yapı Kredi",
+		},
+		{
+			name:     "Kuveyt Türk by labels",
+			input:    "Gönderen Kişi: Test User\nGönderilen IBAN: TR00",
+			expected: "Kuveyt Türk",
+		},
+		{
+			name:     "HalkBank by amount label",
+			input:    "ALICI : Test User\nİŞLEM TUTARI (TL) : 100.00",
+			expected: "HalkBank",
+		},
+		{
+			name:     "bank name outweighs another bank's labels",
+			input:    "HALKBANK\nALICI ADI: Test User",
+			expected: "HalkBank",
+		},
+		{
+			name:     "no bank detected",
+			input:    "ALICI: Test User\nTUTAR: 100.00 TL",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, _ := defaultRegistry.Detect(tt.input)
+			result := ""
+			if parser != nil {
+				result = parser.Name()
+			}
+			if result != tt.expected {
+				t.Errorf("Detect() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParserRegistryRunsOnlyWinningParser(t *testing.T) {
+	// The Yapı Kredi amount label must not fill the amount of a VakıfBank receipt
+	input := "VAKIFBANK\nALICI AD SOYAD/UNVAN: Test User\nGİDEN EFT TUTARI: 100.00"
+
+	receipt, parser := defaultRegistry.Parse(input)
+	if parser == nil || parser.Name() != "VakıfBank" {
+		t.Fatalf("Parse() parser = %v, want VakıfBank", parser)
+	}
+	if receipt.Recipient != "Test User" {
+		t.Errorf("Parse() recipient = %q, want %q", receipt.Recipient, "Test User")
+	}
+	if receipt.AmountText != "" {
+		t.Errorf("Parse() amount = %q, want empty", receipt.AmountText)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pageSeparator marks page boundaries in the text handed to the parsers.
+// Field patterns stop at line breaks, so values never run across pages.
+const pageSeparator = "\n\f\n"
+
+// extractPages returns the plain text of the first maxPages pages of the
+// document, or of all pages when maxPages is zero. Pages without content
+// are kept as empty strings so page numbers stay aligned.
+func extractPages(r *pdf.Reader, maxPages int) ([]string, error) {
+	numPages := r.NumPage()
+	if maxPages > 0 && numPages > maxPages {
+		numPages = maxPages
+	}
+
+	pages := make([]string, 0, numPages)
+	for i := 1; i <= numPages; i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			pages = append(pages, "")
+			continue
+		}
+
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text of page %d: %w", i, err)
+		}
+		pages = append(pages, text)
+	}
+
+	return pages, nil
+}
+
+// joinPages concatenates page texts, keeping the page boundaries
+func joinPages(pages []string) string {
+	return strings.Join(pages, pageSeparator)
+}
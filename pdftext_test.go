@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// testPDFTurkishCodes maps the Turkish letters missing from WinAnsi to the
+// codes the test font's Differences array assigns them
+var testPDFTurkishCodes = map[rune]byte{'Ğ': 128, 'ğ': 129, 'İ': 130, 'ı': 131, 'Ş': 132, 'ş': 133}
+
+// testPDFString encodes line as a PDF string in the test font's encoding
+func testPDFString(line string) string {
+	var b strings.Builder
+	for _, r := range line {
+		if code, ok := testPDFTurkishCodes[r]; ok {
+			b.WriteByte(code)
+			continue
+		}
+		if r == '(' || r == ')' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		if r > 0xff {
+			r = '?'
+		}
+		b.WriteByte(byte(r))
+	}
+	return b.String()
+}
+
+// buildTestPDF writes a minimal PDF with one Helvetica text line per entry
+// of every page
+func buildTestPDF(pages ...[]string) []byte {
+	var objects []string
+	pageRefs := make([]string, 0, len(pages))
+	firstPage := 4
+	for i, lines := range pages {
+		pageObj := firstPage + i*2
+		pageRefs = append(pageRefs, fmt.Sprintf("%d 0 R", pageObj))
+
+		var content strings.Builder
+		content.WriteString("BT /F1 10 Tf 50 800 Td 12 TL\n")
+		for _, line := range lines {
+			content.WriteString(fmt.Sprintf("(%s) Tj T*\n", testPDFString(line)))
+		}
+		content.WriteString("ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] "+
+				"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageObj+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageRefs, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /Type /Encoding " +
+			"/BaseEncoding /WinAnsiEncoding /Differences [128 /Gbreve /gbreve /Idotaccent /dotlessi /Scedilla /scedilla] >> >>",
+	}, objects...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, object))
+	}
+
+	xref := buf.Len()
+	buf.WriteString(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(objects)+1))
+	for _, offset := range offsets {
+		buf.WriteString(fmt.Sprintf("%010d 00000 n \n", offset))
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref))
+
+	return buf.Bytes()
+}
+
+// openTestPDF parses data built by buildTestPDF
+func openTestPDF(t *testing.T, data []byte) *pdf.Reader {
+	t.Helper()
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("pdf.NewReader() error = %v", err)
+	}
+	return r
+}
+
+func TestExtractPages(t *testing.T) {
+	data := buildTestPDF(
+		[]string{"ALICI: Test User", "ACIKLAMA: Rent"},
+		[]string{"TUTAR: 1,500.00 TL"},
+		[]string{"Sayfa 3"},
+	)
+
+	tests := []struct {
+		name     string
+		maxPages int
+		expected int
+	}{
+		{name: "all pages", maxPages: 0, expected: 3},
+		{name: "capped pages", maxPages: 2, expected: 2},
+		{name: "cap above page count", maxPages: 10, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := extractPages(openTestPDF(t, data), tt.maxPages)
+			if err != nil {
+				t.Fatalf("extractPages() error = %v", err)
+			}
+			if len(pages) != tt.expected {
+				t.Errorf("extractPages() returned %d pages, want %d", len(pages), tt.expected)
+			}
+		})
+	}
+}
+
+func TestAmountOnSecondPage(t *testing.T) {
+	data := buildTestPDF(
+		[]string{"ALICI: Test User", "ACIKLAMA: Rent"},
+		[]string{"TUTAR: 1,500.00 TL"},
+	)
+
+	pages, err := extractPages(openTestPDF(t, data), 0)
+	if err != nil {
+		t.Fatalf("extractPages() error = %v", err)
+	}
+
+	text := joinPages(pages)
+	if strings.Count(text, pageSeparator) != 1 {
+		t.Errorf("joinPages() = %q, want one page separator", text)
+	}
+
+	receipt := extractReceipt(text)
+	if receipt == nil || receipt.Recipient != "Test User" || receipt.AmountText != "1,500.00" {
+		t.Errorf("extractReceipt() = %+v, want recipient and amount from both pages", receipt)
+	}
+}
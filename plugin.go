@@ -16,9 +16,11 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ledongthuc/pdf"
@@ -32,6 +34,7 @@ type Configuration struct {
 	ProcessOnlyInChannels    bool   `json:"ProcessOnlyInChannels"`
 	AllowedChannels          string `json:"AllowedChannels"`
 	MaxFileSizeMB            int    `json:"MaxFileSizeMB"`
+	MaxPages                 int    `json:"MaxPages"`
 	CustomMessagePrefix      string `json:"CustomMessagePrefix"`
 	IncludeTimestamp         bool   `json:"IncludeTimestamp"`
 	HideCredits              bool   `json:"HideCredits"`
@@ -39,13 +42,55 @@ type Configuration struct {
 	ErrorNotificationMessage string `json:"ErrorNotificationMessage"`
 	EnableDebugLogging       bool   `json:"EnableDebugLogging"`
 	SupportedBanks           string `json:"SupportedBanks"`
+	BankConfidenceThreshold  int    `json:"BankConfidenceThreshold"`
+	OutputMode               string `json:"OutputMode"`
+	DetectDuplicates         bool   `json:"DetectDuplicates"`
+	AmountLocale             string `json:"AmountLocale"`
+	ShowFullIBAN             bool   `json:"ShowFullIBAN"`
+	FlagLowConfidenceFields  bool   `json:"FlagLowConfidenceFields"`
+	CustomBankTemplates      string `json:"CustomBankTemplates"`
+	DigestSchedule           string `json:"DigestSchedule"`
+	DigestChannels           string `json:"DigestChannels"`
+	DigestHour               int    `json:"DigestHour"`
+	WebhookURLs              string `json:"WebhookURLs"`
+	WebhookSecret            string `json:"WebhookSecret"`
+
+	// registry holds the built-in parsers and those of CustomBankTemplates
+	registry *parserRegistry
+	// webhookURLs holds the parsed WebhookURLs
+	webhookURLs []string
+}
+
+// parserRegistry returns the parsers of the configuration, falling back to
+// the built-in parsers
+func (c *Configuration) parserRegistry() *parserRegistry {
+	if c.registry == nil {
+		return defaultRegistry
+	}
+	return c.registry
 }
 
 // Plugin represents the main plugin instance.
 // Developed by SkyLostTR (@Keeftraum) for the Mattermost community
 type Plugin struct {
 	plugin.MattermostPlugin
-	configuration *Configuration
+
+	// configurationLock synchronizes access to the configuration, which
+	// OnConfigurationChange replaces while hooks and background jobs read it
+	configurationLock sync.RWMutex
+	configuration     *Configuration
+
+	// botUserID is the user ID of the bot the plugin posts as
+	botUserID string
+
+	// store persists parsed transactions in the plugin KV store
+	store *transactionStore
+
+	// router serves the REST API
+	router *http.ServeMux
+
+	// jobsStop stops the background jobs
+	jobsStop chan struct{}
 }
 
 // OnActivate is called when the plugin is activated.
@@ -54,6 +99,21 @@ func (p *Plugin) OnActivate() error {
 		return err
 	}
 
+	botUserID, err := p.ensureBotUser()
+	if err != nil {
+		p.API.LogError("Failed to ensure bot user", "error", err.Error())
+		return err
+	}
+	p.botUserID = botUserID
+	p.store = newTransactionStore(p.API)
+	p.router = p.newAPIRouter()
+	p.startJobs()
+
+	if err := p.API.RegisterCommand(getCommand()); err != nil {
+		p.API.LogError("Failed to register slash command", "error", err.Error())
+		return err
+	}
+
 	p.API.LogInfo("PDF Dekont Parser Plugin activated successfully",
 		"version", "1.1.0",
 		"author", "SkyLostTR (@Keeftraum)",
@@ -62,6 +122,12 @@ func (p *Plugin) OnActivate() error {
 	return nil
 }
 
+// OnDeactivate is called when the plugin is deactivated.
+func (p *Plugin) OnDeactivate() error {
+	p.stopJobs()
+	return nil
+}
+
 // OnConfigurationChange is called when the plugin configuration changes
 func (p *Plugin) OnConfigurationChange() error {
 	var configuration = new(Configuration)
@@ -75,20 +141,64 @@ func (p *Plugin) OnConfigurationChange() error {
 	if configuration.MaxFileSizeMB == 0 {
 		configuration.MaxFileSizeMB = 10
 	}
+	if configuration.MaxPages <= 0 {
+		configuration.MaxPages = 20
+	}
 	if configuration.CustomMessagePrefix == "" {
 		configuration.CustomMessagePrefix = "📄 **Dekont Bilgileri:**"
 	}
+	if configuration.BankConfidenceThreshold == 0 {
+		configuration.BankConfidenceThreshold = 60
+	}
+	if configuration.OutputMode == "" {
+		configuration.OutputMode = outputModeReply
+	}
+	if configuration.DigestHour < 0 || configuration.DigestHour > 23 {
+		configuration.DigestHour = defaultDigestHour
+	}
+	if _, ok := numberFormats[configuration.AmountLocale]; !ok {
+		configuration.AmountLocale = defaultAmountLocale
+	}
 	if configuration.ErrorNotificationMessage == "" {
 		configuration.ErrorNotificationMessage = "⚠️ PDF dekont işlenirken hata oluştu. Lütfen dosyanın geçerli bir banka dekontu olduğundan emin olun."
 	}
 
-	p.configuration = configuration
+	// Invalid settings keep the active configuration. During activation there
+	// is none, so the plugin starts without them instead of failing.
+	p.configurationLock.RLock()
+	activating := p.configuration == nil
+	p.configurationLock.RUnlock()
+
+	templates, err := parseBankTemplates(configuration.CustomBankTemplates)
+	if err != nil {
+		p.API.LogError("Invalid custom bank templates", "error", err.Error())
+		if !activating {
+			return fmt.Errorf("invalid custom bank templates: %w", err)
+		}
+		templates = nil
+	}
+	if len(templates) > 0 {
+		configuration.registry = newTemplateRegistry(templates)
+	}
+
+	webhookURLs, err := parseWebhookURLs(configuration.WebhookURLs)
+	if err != nil {
+		p.API.LogError("Invalid webhook URLs", "error", err.Error())
+		if !activating {
+			return err
+		}
+		webhookURLs = nil
+	}
+	configuration.webhookURLs = webhookURLs
+
+	p.setConfiguration(configuration)
 
 	if configuration.EnableDebugLogging {
 		p.API.LogDebug("Plugin configuration updated",
 			"EnablePlugin", configuration.EnablePlugin,
 			"ProcessOnlyInChannels", configuration.ProcessOnlyInChannels,
-			"MaxFileSizeMB", configuration.MaxFileSizeMB)
+			"MaxFileSizeMB", configuration.MaxFileSizeMB,
+			"customBanks", len(templates))
 	}
 
 	return nil
@@ -96,12 +206,21 @@ func (p *Plugin) OnConfigurationChange() error {
 
 // getConfiguration retrieves the active configuration under lock
 func (p *Plugin) getConfiguration() *Configuration {
+	p.configurationLock.RLock()
+	defer p.configurationLock.RUnlock()
 	if p.configuration == nil {
 		return &Configuration{}
 	}
 	return p.configuration
 }
 
+// setConfiguration replaces the active configuration under lock
+func (p *Plugin) setConfiguration(configuration *Configuration) {
+	p.configurationLock.Lock()
+	defer p.configurationLock.Unlock()
+	p.configuration = configuration
+}
+
 // MessageHasBeenPosted processes newly posted messages to extract PDF content.
 func (p *Plugin) MessageHasBeenPosted(_ *plugin.Context, post *model.Post) {
 	config := p.getConfiguration()
@@ -111,7 +230,7 @@ func (p *Plugin) MessageHasBeenPosted(_ *plugin.Context, post *model.Post) {
 		return
 	}
 
-	if post.Type != "" || post.FileIds == nil || len(post.FileIds) == 0 {
+	if post.Type != "" || post.UserId == p.botUserID || len(post.FileIds) == 0 {
 		return
 	}
 
@@ -142,39 +261,180 @@ func (p *Plugin) MessageHasBeenPosted(_ *plugin.Context, post *model.Post) {
 		}
 	}
 
-	for _, fileID := range post.FileIds {
-		if err := p.processFileUpload(fileID, post); err != nil {
-			p.API.LogError("Failed to process file upload",
-				"fileId", fileID,
-				"error", err.Error())
+	failed, err := p.processPost(post)
+	if err != nil {
+		p.API.LogError("Failed to publish receipts", "postId", post.Id, "error", err.Error())
+	}
 
-			// Send error notification if enabled
-			if config.NotifyOnProcessingError {
-				p.sendErrorNotification(post.ChannelId, config.ErrorNotificationMessage)
-			}
+	// Send error notification if enabled and nothing reported the failures
+	if config.NotifyOnProcessingError && (err != nil || len(failed) > 0) {
+		message := config.ErrorNotificationMessage
+		if len(failed) > 0 {
+			message += "\n\n" + formatAttachmentErrors(failed)
 		}
+		p.sendErrorNotification(post, message)
 	}
 }
 
-// sendErrorNotification sends an error message to the channel
-func (p *Plugin) sendErrorNotification(channelID, message string) {
-	post := &model.Post{
-		ChannelId: channelID,
+// sendErrorNotification replies to the failed post with an error message
+// posted by the plugin bot
+func (p *Plugin) sendErrorNotification(post *model.Post, message string) {
+	errorPost := &model.Post{
+		ChannelId: post.ChannelId,
+		RootId:    threadRootID(post),
+		UserId:    p.botUserID,
 		Message:   message,
 		Type:      "custom_pdf_error",
 	}
 
-	if _, err := p.API.CreatePost(post); err != nil {
+	if _, err := p.API.CreatePost(errorPost); err != nil {
 		p.API.LogError("Failed to send error notification", "error", err.Error())
 	}
 }
 
-func (p *Plugin) processFileUpload(fileID string, post *model.Post) error {
+// attachmentResult is the outcome of parsing one PDF attachment of a post
+type attachmentResult struct {
+	fileInfo   *model.FileInfo
+	data       []byte
+	receipts   []*Receipt
+	duplicates int
+	err        error
+}
+
+// failure describes why no receipt of the attachment is shown, or returns an
+// empty string when it has receipts
+func (ar *attachmentResult) failure() string {
+	switch {
+	case ar.err != nil:
+		return "dosya okunamadı"
+	case len(ar.receipts) > 0:
+		return ""
+	case ar.duplicates > 0:
+		return "dekontlar daha önce paylaşılmış"
+	default:
+		return "dekont bulunamadı"
+	}
+}
+
+// readAttachments parses the PDF attachments of post. A file that fails keeps
+// its error so the other files are still processed; files that are not PDFs
+// or exceed the size limit are left out.
+func (p *Plugin) readAttachments(post *model.Post, config *Configuration) []*attachmentResult {
+	var results []*attachmentResult
+	for _, fileID := range post.FileIds {
+		fileInfo, data, receipts, err := p.readReceipts(fileID, config)
+		if err != nil {
+			p.API.LogError("Failed to process file upload",
+				"fileId", fileID,
+				"error", err.Error())
+		}
+		if fileInfo == nil {
+			continue
+		}
+		results = append(results, &attachmentResult{fileInfo: fileInfo, data: data, receipts: receipts, err: err})
+	}
+	return results
+}
+
+// processPost parses the receipts of all PDF attachments of post, publishes
+// them in a single rendering and stores them as transactions. The rendering
+// lists the attachments without receipts; when nothing is published, the
+// attachments that failed are returned instead.
+func (p *Plugin) processPost(post *model.Post) ([]*attachmentResult, error) {
 	config := p.getConfiguration()
 
-	fileInfo, err := p.API.GetFileInfo(fileID)
-	if err != nil || !strings.HasSuffix(fileInfo.Name, ".pdf") {
-		return nil // Not a PDF file, skip silently
+	results := p.readAttachments(post, config)
+	var claimed []string
+	for _, result := range results {
+		if !config.DetectDuplicates || len(result.receipts) == 0 {
+			continue
+		}
+		receipts, duplicates, keys, err := p.claimReceipts(post, result.data, result.receipts)
+		if err != nil {
+			p.API.LogError("Failed to check duplicate receipts",
+				"fileId", result.fileInfo.Id,
+				"error", err.Error())
+			result.receipts, result.err = nil, err
+			continue
+		}
+		if len(duplicates) > 0 {
+			p.sendDuplicateNotice(post, duplicates)
+		}
+		result.receipts, result.duplicates = receipts, len(duplicates)
+		claimed = append(claimed, keys...)
+	}
+
+	var published, failed []*attachmentResult
+	for _, result := range results {
+		if len(result.receipts) > 0 {
+			published = append(published, result)
+			continue
+		}
+		if result.err != nil {
+			failed = append(failed, result)
+		}
+		if result.duplicates == 0 {
+			p.saveFailure(post, result, result.failure())
+		}
+	}
+	if len(published) == 0 {
+		return failed, nil
+	}
+
+	if err := p.publishReceipts(post, results, config); err != nil {
+		p.releaseFingerprints(claimed)
+		return nil, err
+	}
+
+	var created []*Transaction
+	for _, result := range published {
+		transactions, err := p.saveTransactions(post, result.fileInfo, result.receipts)
+		if err != nil {
+			p.API.LogError("Failed to save parsed transactions",
+				"fileId", result.fileInfo.Id,
+				"error", err.Error())
+		}
+		created = append(created, transactions...)
+
+		if config.EnableDebugLogging {
+			p.API.LogDebug("Successfully processed PDF and published receipts",
+				"fileName", result.fileInfo.Name,
+				"receipts", len(result.receipts),
+				"outputMode", config.OutputMode)
+		}
+	}
+	if p.queueWebhooks(created, config) > 0 {
+		go p.processWebhookQueue(time.Now())
+	}
+
+	return nil, nil
+}
+
+// saveFailure records an attachment no receipt was parsed from for the
+// channel digests
+func (p *Plugin) saveFailure(post *model.Post, result *attachmentResult, reason string) {
+	failure := &ParseFailure{
+		FileID:    result.fileInfo.Id,
+		FileName:  result.fileInfo.Name,
+		PostID:    post.Id,
+		ChannelID: post.ChannelId,
+		CreateAt:  model.GetMillis(),
+		Reason:    reason,
+	}
+	if err := p.store.SaveFailure(failure); err != nil {
+		p.API.LogError("Failed to save parse failure",
+			"fileId", failure.FileID,
+			"error", err.Error())
+	}
+}
+
+// readReceipts downloads a PDF attachment and parses its receipts. Files that
+// are not PDFs or exceed the size limit are skipped without an error and
+// without file info.
+func (p *Plugin) readReceipts(fileID string, config *Configuration) (*model.FileInfo, []byte, []*Receipt, error) {
+	fileInfo, appErr := p.API.GetFileInfo(fileID)
+	if appErr != nil || !strings.HasSuffix(fileInfo.Name, ".pdf") {
+		return nil, nil, nil, nil // Not a PDF file, skip silently
 	}
 
 	if config.EnableDebugLogging {
@@ -193,17 +453,28 @@ func (p *Plugin) processFileUpload(fileID string, post *model.Post) error {
 				"fileSize", fileInfo.Size,
 				"maxSize", maxSizeBytes)
 		}
-		return nil
+		return nil, nil, nil, nil
 	}
 
 	data, appErr := p.API.GetFile(fileID)
 	if appErr != nil {
-		return appErr
+		return fileInfo, nil, nil, appErr
+	}
+
+	pages, err := p.extractPDFPages(fileInfo, data, config)
+	if err != nil {
+		return fileInfo, nil, nil, err
 	}
 
+	return fileInfo, data, p.parseReceipts(pages, config), nil
+}
+
+// extractPDFPages writes the PDF to a temporary file and extracts the text of
+// its pages
+func (p *Plugin) extractPDFPages(fileInfo *model.FileInfo, data []byte, config *Configuration) ([]string, error) {
 	tempFile, fileErr := os.CreateTemp("", "*.pdf")
 	if fileErr != nil {
-		return fileErr
+		return nil, fileErr
 	}
 	defer func() {
 		if err := tempFile.Close(); err != nil {
@@ -215,277 +486,59 @@ func (p *Plugin) processFileUpload(fileID string, post *model.Post) error {
 	}()
 
 	if _, writeErr := tempFile.Write(data); writeErr != nil {
-		return writeErr
+		return nil, writeErr
 	}
 
 	if closeErr := tempFile.Close(); closeErr != nil {
-		return closeErr
+		return nil, closeErr
 	}
 
 	file, r, pdfErr := pdf.Open(tempFile.Name())
 	if pdfErr != nil {
-		return pdfErr
+		return nil, pdfErr
 	}
 	defer file.Close()
 
-	var extractedText string
-	if r.NumPage() > 0 {
-		page := r.Page(1)
-		if page.V.IsNull() {
-			return nil
-		}
-		var textErr error
-		extractedText, textErr = page.GetPlainText(nil)
-		if textErr != nil {
-			return textErr
-		}
+	pages, textErr := extractPages(r, config.MaxPages)
+	if textErr != nil {
+		return nil, textErr
 	}
 
-	description := p.extractFields(extractedText, config)
-	if description != "" {
-		// Add custom prefix and timestamp if enabled
-		var fullMessage strings.Builder
-
-		if config.CustomMessagePrefix != "" {
-			fullMessage.WriteString(config.CustomMessagePrefix)
-			fullMessage.WriteString("\n\n")
-		}
-
-		fullMessage.WriteString(description)
-
-		if config.IncludeTimestamp {
-			timestamp := time.Now().Format("02.01.2006 15:04:05")
-			fullMessage.WriteString(fmt.Sprintf("\n\n*İşlenme Zamanı: %s*", timestamp))
-		}
-
-		// Add credits footer (if not hidden)
-		if !config.HideCredits {
-			fullMessage.WriteString("\n\n---\n*Mattermost PDF Parser Plugin by SkyLostTR* 🚀")
-		}
-
-		post.Message = fullMessage.String()
-		_, appErr = p.API.UpdatePost(post)
-		if appErr != nil {
-			return appErr
-		}
-
-		if config.EnableDebugLogging {
-			p.API.LogDebug("Successfully processed PDF and updated post",
-				"fileName", fileInfo.Name,
-				"extractedFields", len(strings.Split(description, "\n")))
-		}
+	if config.EnableDebugLogging && r.NumPage() > len(pages) {
+		p.API.LogDebug("PDF has more pages than the configured limit",
+			"fileName", fileInfo.Name,
+			"numPages", r.NumPage(),
+			"maxPages", config.MaxPages)
 	}
 
-	return nil
+	return pages, nil
 }
 
-// extractFields extracts transaction details from PDF text
+// parseReceipts splits the document into receipts and parses each one with
+// the parser of its issuing bank
 // Enhanced by SkyLostTR (@Keeftraum) to support multiple Turkish bank formats
-func (p *Plugin) extractFields(text string, config *Configuration) string {
-	var alici, gonderen, aciklama, tutar, tarih string
+func (p *Plugin) parseReceipts(pages []string, config *Configuration) []*Receipt {
+	receipts := config.parserRegistry().extractReceipts(pages)
 
 	if config.EnableDebugLogging {
-		p.API.LogDebug("Starting field extraction",
-			"textLength", len(text),
-			"author", "SkyLostTR (@Keeftraum)")
-	}
-
-	// Enhanced regex patterns for multiple bank formats
-	// VakıfBank patterns
-	reAliciVakif := regexp.MustCompile(`(?i)ALICI\s*(?:AD\s*SOYAD/UNVAN)?\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reGonderenVakif := regexp.MustCompile(`(?i)G[ÖO]NDEREN\s*(?:AD\s*SOYAD\s*/?\s*UNVAN)?\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reAciklamaVakif := regexp.MustCompile(`(?i)İ[ŞS]LEM\s*A[ÇC]IKLAMASI\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reTutarVakif := regexp.MustCompile(`(?i)İ[ŞS]LEM\s*TUTARI\s*[:\-]?\s*(?:.*?)?([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*(?:TL|₺)?`)
-	reTarihVakif := regexp.MustCompile(`(?i)İ[ŞS]LEM\s*TARİHİ\s*[:\-]?\s*(.+?)(?:\n|$)`)
-
-	// YapıKredi patterns
-	reAliciYapi := regexp.MustCompile(`(?i)ALICI\s*ADI\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reGonderenYapi := regexp.MustCompile(`(?i)G[ÖO]NDEREN\s*ADI\s*SOYAD\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reAciklamaYapi := regexp.MustCompile(`(?i)A[ÇC]IKLAMA\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reTutarYapi := regexp.MustCompile(`(?i)G[İI]DEN\s*EFT\s*TUTARI\s*[:\-]?\s*(?:.*?)?([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*(?:TL|₺)?`)
-
-	// Kuveyt Türk patterns
-	reAliciKuveyt := regexp.MustCompile(`(?i)ALICI\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reGonderenKuveyt := regexp.MustCompile(`(?i)G[ÖO]NDEREN\s*(?:KİŞİ|KISI)\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reAciklamaKuveyt := regexp.MustCompile(`(?i)A[ÇC]IKLAMA\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reTutarKuveyt := regexp.MustCompile(`(?i)TUTAR\s*[:\-]?\s*(?:.*?)?([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*(?:TL|₺)?`)
-
-	// HalkBank patterns
-	reAliciHalk := regexp.MustCompile(`(?i)ALICI\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reGonderenHalk := regexp.MustCompile(`(?i)G[ÖO]NDEREN\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reAciklamaHalk := regexp.MustCompile(`(?i)A[ÇC]IKLAMA\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reTutarHalk := regexp.MustCompile(`(?i)İ[ŞS]LEM\s*TUTARI\s*\(TL\)\s*[:\-]?\s*(?:.*?)?([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*(?:TL|₺)?`)
-	reTarihHalk := regexp.MustCompile(`(?i)İ[ŞS]LEM\s*TARİHİ\s*[:\-]?\s*(.+?)(?:\n|$)`)
-
-	// Generic patterns (for existing banks and fallback)
-	reAlici := regexp.MustCompile(`(?i)ALICI\s*(?:AD\s*SOYAD/UNVAN)?\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reAciklama := regexp.MustCompile(`(?i)A[CÇ]IKLAMA\s*[:\-]?\s*(.+?)(?:\n|$)`)
-	reTutar := regexp.MustCompile(`(?i)(?:İ[ŞS]LEM\s*TUTARI|I[ŞS]LEM\s*TUTARI|TUTAR[IİĞ]?|HAVALE\s*TUTARI|G[İI]DEN\s*EFT\s*TUTARI|EFT\s*TUTARI|TRANSFER\s*TUTARI|PARA\s*TUTARI|M[İI]KTAR)\s*(?:\(TL\))?\s*[:\-\s]*(?:.*?)?([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*(?:TL|₺)?`)
-
-	// Try bank-specific patterns first, then fall back to generic patterns
-
-	// VakıfBank
-	if m := reAliciVakif.FindStringSubmatch(text); len(m) > 1 {
-		alici = strings.TrimSpace(m[1])
-	}
-	if m := reGonderenVakif.FindStringSubmatch(text); len(m) > 1 {
-		gonderen = strings.TrimSpace(m[1])
-	}
-	if m := reAciklamaVakif.FindStringSubmatch(text); len(m) > 1 {
-		aciklama = strings.TrimSpace(m[1])
-	}
-	if m := reTutarVakif.FindStringSubmatch(text); len(m) > 1 {
-		tutar = strings.TrimSpace(m[1])
-	}
-	if m := reTarihVakif.FindStringSubmatch(text); len(m) > 1 {
-		tarih = strings.TrimSpace(m[1])
-	}
-
-	// YapıKredi
-	if alici == "" {
-		if m := reAliciYapi.FindStringSubmatch(text); len(m) > 1 {
-			alici = strings.TrimSpace(m[1])
-		}
-	}
-	if gonderen == "" {
-		if m := reGonderenYapi.FindStringSubmatch(text); len(m) > 1 {
-			gonderen = strings.TrimSpace(m[1])
-		}
-	}
-	if aciklama == "" {
-		if m := reAciklamaYapi.FindStringSubmatch(text); len(m) > 1 {
-			aciklama = strings.TrimSpace(m[1])
-		}
-	}
-	if tutar == "" {
-		if m := reTutarYapi.FindStringSubmatch(text); len(m) > 1 {
-			tutar = strings.TrimSpace(m[1])
-		}
-	}
-
-	// Kuveyt Türk
-	if alici == "" {
-		if m := reAliciKuveyt.FindStringSubmatch(text); len(m) > 1 {
-			alici = strings.TrimSpace(m[1])
-		}
-	}
-	if gonderen == "" {
-		if m := reGonderenKuveyt.FindStringSubmatch(text); len(m) > 1 {
-			gonderen = strings.TrimSpace(m[1])
-		}
-	}
-	if aciklama == "" {
-		if m := reAciklamaKuveyt.FindStringSubmatch(text); len(m) > 1 {
-			aciklama = strings.TrimSpace(m[1])
-		}
-	}
-	if tutar == "" {
-		if m := reTutarKuveyt.FindStringSubmatch(text); len(m) > 1 {
-			tutar = strings.TrimSpace(m[1])
-		}
-	}
-
-	// HalkBank
-	if alici == "" {
-		if m := reAliciHalk.FindStringSubmatch(text); len(m) > 1 {
-			alici = strings.TrimSpace(m[1])
-		}
-	}
-	if gonderen == "" {
-		if m := reGonderenHalk.FindStringSubmatch(text); len(m) > 1 {
-			gonderen = strings.TrimSpace(m[1])
-		}
-	}
-	if aciklama == "" {
-		if m := reAciklamaHalk.FindStringSubmatch(text); len(m) > 1 {
-			aciklama = strings.TrimSpace(m[1])
-		}
-	}
-	if tutar == "" {
-		if m := reTutarHalk.FindStringSubmatch(text); len(m) > 1 {
-			tutar = strings.TrimSpace(m[1])
-		}
-	}
-	if tarih == "" {
-		if m := reTarihHalk.FindStringSubmatch(text); len(m) > 1 {
-			tarih = strings.TrimSpace(m[1])
-		}
-	}
-
-	// Generic fallback patterns
-	if alici == "" {
-		if m := reAlici.FindStringSubmatch(text); len(m) > 1 {
-			alici = strings.TrimSpace(m[1])
-		}
-	}
-	if aciklama == "" {
-		if m := reAciklama.FindStringSubmatch(text); len(m) > 1 {
-			aciklama = strings.TrimSpace(m[1])
-		}
-	}
-	if tutar == "" {
-		if m := reTutar.FindStringSubmatch(text); len(m) > 1 {
-			tutar = strings.TrimSpace(m[1])
-		}
-	}
-
-	// If no specific amount field found, try a more generic approach
-	if tutar == "" {
-		reGenericTutar := regexp.MustCompile(`(?i)([0-9]+(?:[.,][0-9]{1,3})*(?:[.,][0-9]{2})?)\s*(?:TL|₺)`)
-		matches := reGenericTutar.FindAllStringSubmatch(text, -1)
-		if len(matches) > 0 {
-			// Take the first found amount that looks like a transaction amount
-			for _, match := range matches {
-				if len(match) > 1 {
-					tutar = strings.TrimSpace(match[1])
-					break
-				}
-			}
-		}
-	}
-
-	// Clean up extracted values - remove common prefixes and suffixes
-	alici = cleanFieldValue(alici)
-	gonderen = cleanFieldValue(gonderen)
-	aciklama = cleanFieldValue(aciklama)
-	tarih = cleanFieldValue(tarih)
-
-	// Return empty string if no meaningful data was extracted
-	if alici == "" && gonderen == "" && aciklama == "" && tutar == "" && tarih == "" {
-		if config.EnableDebugLogging {
+		text := joinPages(pages)
+		if len(receipts) == 0 {
 			p.API.LogDebug("No meaningful data extracted from PDF text",
 				"textPreview", text[:min(200, len(text))],
 				"author", "SkyLostTR (@Keeftraum)")
 		}
-		return ""
-	}
-
-	// Build the response with available information
-	var result strings.Builder
-
-	if aciklama != "" {
-		result.WriteString(fmt.Sprintf("**Açıklama**: %s\n", aciklama))
-	}
-	if alici != "" {
-		result.WriteString(fmt.Sprintf("**Alıcı**: %s\n", alici))
-	}
-	if gonderen != "" {
-		result.WriteString(fmt.Sprintf("**Gönderen**: %s\n", gonderen))
-	}
-	if tutar != "" {
-		result.WriteString(fmt.Sprintf("**İşlem Tutarı**: %s TL\n", tutar))
-	}
-	if tarih != "" {
-		result.WriteString(fmt.Sprintf("**İşlem Tarihi**: %s\n", tarih))
-	}
-
-	if config.EnableDebugLogging {
-		p.API.LogDebug("Successfully extracted fields",
-			"fieldsCount", strings.Count(result.String(), "**"),
-			"author", "SkyLostTR (@Keeftraum)")
+		for i, receipt := range receipts {
+			p.API.LogDebug("Extracted receipt",
+				"index", i,
+				"textLength", len(text),
+				"bank", receipt.Bank,
+				"confidence", receipt.BankConfidence,
+				"fields", receipt.describeSources(),
+				"author", "SkyLostTR (@Keeftraum)")
+		}
 	}
 
-	return strings.TrimRight(result.String(), "\n")
+	return receipts
 }
 
 // min returns the minimum of two integers
@@ -509,7 +562,7 @@ func cleanFieldValue(value string) string {
 		`[:\-\s]+$`,      // Trailing colons, dashes, spaces
 		`^(?i)(TL|₺)\s*`, // Leading currency symbols
 		`\s*(?i)(TL|₺)$`, // Trailing currency symbols
-		`^\d+\.\s*`,      // Leading numbers with dots (line numbers)
+		`^\d+\.\s+`,      // Leading numbers with dots (line numbers)
 		`^\s*[-–—]\s*`,   // Leading dashes
 		`\s*[-–—]\s*$`,   // Trailing dashes
 	}
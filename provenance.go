@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FieldConfidence tells how certain an extracted field value is
+type FieldConfidence int
+
+// Confidence levels of extracted fields. A bank specific label is the most
+// reliable source, the generic labels less so and unlabelled values, such as
+// the first date in the text, the least.
+const (
+	confidenceLow FieldConfidence = iota + 1
+	confidenceMedium
+	confidenceHigh
+)
+
+// String returns the name of the confidence level
+func (c FieldConfidence) String() string {
+	switch c {
+	case confidenceHigh:
+		return "high"
+	case confidenceMedium:
+		return "medium"
+	case confidenceLow:
+		return "low"
+	default:
+		return "unknown"
+	}
+}
+
+// FieldSource records the rule an extracted field value came from
+type FieldSource struct {
+	Rule       string
+	Confidence FieldConfidence
+}
+
+// Receipt field names used as keys of Receipt.Sources, matching the JSON names
+const (
+	fieldRecipient     = "recipient"
+	fieldRecipientIBAN = "recipient_iban"
+	fieldSender        = "sender"
+	fieldSenderIBAN    = "sender_iban"
+	fieldDescription   = "description"
+	fieldAmount        = "amount"
+	fieldLocalAmount   = "local_amount"
+	fieldExchangeRate  = "exchange_rate"
+	fieldFee           = "fee"
+	fieldCommission    = "commission"
+	fieldTax           = "tax"
+	fieldTotal         = "total"
+	fieldDate          = "date"
+	fieldReference     = "reference_number"
+	fieldType          = "type"
+)
+
+// setSource records where the value of field came from
+func (r *Receipt) setSource(field string, source FieldSource) {
+	if r.Sources == nil {
+		r.Sources = map[string]FieldSource{}
+	}
+	r.Sources[field] = source
+}
+
+// lowConfidence reports whether the value of field came from a low
+// confidence rule
+func (r *Receipt) lowConfidence(field string) bool {
+	return r.Sources[field].Confidence == confidenceLow
+}
+
+// describeSources lists the rule and confidence of every extracted field,
+// sorted by field name, for debug logs
+func (r *Receipt) describeSources() string {
+	fields := make([]string, 0, len(r.Sources))
+	for field := range r.Sources {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	descriptions := make([]string, len(fields))
+	for i, field := range fields {
+		source := r.Sources[field]
+		descriptions[i] = fmt.Sprintf("%s=%s (%s)", field, source.Rule, source.Confidence)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// fieldMatcher finds field values with a parser's patterns and records their
+// source on the receipt. Every pattern after the first lowers the confidence
+// by a level, as later patterns are looser fallbacks.
+type fieldMatcher struct {
+	receipt *Receipt
+	owner   string          // parser name, or "shared" for the shared patterns
+	base    FieldConfidence // confidence of a match of the first pattern
+}
+
+// submatch returns the submatches of the first regex whose first capture
+// group is not blank, or nil when none matches
+func (fm fieldMatcher) submatch(field string, patterns []*regexp.Regexp, text string) []string {
+	for i, re := range patterns {
+		if m := re.FindStringSubmatch(text); len(m) > 1 && strings.TrimSpace(m[1]) != "" {
+			fm.setSource(field, i)
+			return m
+		}
+	}
+	return nil
+}
+
+// setSource records that the value of field was found by the i-th pattern
+func (fm fieldMatcher) setSource(field string, i int) {
+	confidence := fm.base - FieldConfidence(i)
+	if confidence < confidenceLow {
+		confidence = confidenceLow
+	}
+	fm.receipt.setSource(field, FieldSource{Rule: fmt.Sprintf("%s.%s[%d]", fm.owner, field, i), Confidence: confidence})
+}
+
+// text returns the cleaned first capture group of the first matching regex
+func (fm fieldMatcher) text(field string, patterns []*regexp.Regexp, text string) string {
+	m := fm.submatch(field, patterns, text)
+	if m == nil {
+		return ""
+	}
+	value := cleanFieldValue(strings.TrimSpace(m[1]))
+	if value == "" {
+		delete(fm.receipt.Sources, field)
+	}
+	return value
+}
+
+// partyDetailLabel matches the values captured after a sender or recipient
+// label that continue the label instead, as in "ALICI IBAN" or
+// "GÖNDEREN BANKA", so they are not taken as the name
+var partyDetailLabel = regexp.MustCompile(`(?i)^(?:IBAN|BANKA(?:S[Iı])?|HESAP|[ŞS]UBE(?:S[İIıi])?)(?:[^\pL]|$)`)
+
+// party returns the cleaned sender or recipient name of the first matching
+// regex. Every match of a regex is tried, skipping the IBAN, bank, account
+// and branch lines of the party, as RE2 has no lookahead to exclude them.
+func (fm fieldMatcher) party(field string, patterns []*regexp.Regexp, text string) string {
+	for i, re := range patterns {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			value := strings.TrimSpace(m[1])
+			if partyDetailLabel.MatchString(value) {
+				continue
+			}
+			if value = cleanFieldValue(value); value != "" {
+				fm.setSource(field, i)
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// amount returns the amount captured by the first matching regex and the
+// currency printed next to it
+func (fm fieldMatcher) amount(field string, patterns []*regexp.Regexp, text string) (Amount, string) {
+	m := fm.submatch(field, patterns, text)
+	if m == nil {
+		return 0, ""
+	}
+	amount, ok := parseAmount(m[1])
+	if !ok {
+		delete(fm.receipt.Sources, field)
+		return 0, ""
+	}
+	return amount, detectCurrency(m[0])
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldSources(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		field      string
+		rule       string
+		confidence FieldConfidence
+	}{
+		{
+			name:       "bank specific pattern",
+			input:      "HALKBANK\nALICI : Test User\nİŞLEM TUTARI (TL) : 100,00",
+			field:      fieldAmount,
+			rule:       "HalkBank.amount[0]",
+			confidence: confidenceHigh,
+		},
+		{
+			name:       "bank specific fallback pattern",
+			input:      "YAPI KREDİ\nALICI ADI: Test User\nTUTARI: 100,00",
+			field:      fieldAmount,
+			rule:       "Yapı Kredi.amount[1]",
+			confidence: confidenceMedium,
+		},
+		{
+			name:       "generic label",
+			input:      "ALICI: Test User\nTUTAR: 100,00 TL",
+			field:      fieldRecipient,
+			rule:       "Generic.recipient[0]",
+			confidence: confidenceMedium,
+		},
+		{
+			name:       "unlabelled amount",
+			input:      "ALICI: Test User\nÖdenen 250,75 TL",
+			field:      fieldAmount,
+			rule:       "Generic.amount[1]",
+			confidence: confidenceLow,
+		},
+		{
+			name:       "unlabelled date",
+			input:      "ALICI: Test User\nTUTAR: 100,00 TL\n15.07.2025",
+			field:      fieldDate,
+			rule:       "shared.date[2]",
+			confidence: confidenceLow,
+		},
+		{
+			name:       "total taken as the amount",
+			input:      "ALICI: Test User\nTOPLAM TUTAR: 100,00 TL",
+			field:      fieldAmount,
+			rule:       "Generic.amount[0]",
+			confidence: confidenceLow,
+		},
+		{
+			name:       "labelled IBAN",
+			input:      "ALICI: Test User\nALICI IBAN: " + testIsBankIBAN,
+			field:      fieldRecipientIBAN,
+			rule:       "iban.label",
+			confidence: confidenceHigh,
+		},
+		{
+			name:       "unlabelled IBAN",
+			input:      "ALICI: Test User\nAÇIKLAMA: Kira\nHESAP: " + testIsBankIBAN,
+			field:      fieldSenderIBAN,
+			rule:       "iban.order",
+			confidence: confidenceLow,
+		},
+		{
+			name:       "transaction type keyword",
+			input:      "ALICI: Test User\nHAVALE TUTARI: 100,00 TL",
+			field:      fieldType,
+			rule:       "type.havale",
+			confidence: confidenceHigh,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := extractReceipt(tt.input)
+			if receipt == nil {
+				t.Fatal("extractReceipt() = nil, want receipt")
+			}
+			source := receipt.Sources[tt.field]
+			if source.Rule != tt.rule || source.Confidence != tt.confidence {
+				t.Errorf("Sources[%q] = %s (%s), want %s (%s)", tt.field, source.Rule, source.Confidence, tt.rule, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestFieldSourcesOfEmptyValues(t *testing.T) {
+	receipt := extractReceipt("ALICI: Test User\nAÇIKLAMA: —\nTUTAR: 100,00 TL")
+	if receipt == nil {
+		t.Fatal("extractReceipt() = nil, want receipt")
+	}
+	if _, ok := receipt.Sources[fieldDescription]; ok || receipt.Description != "" {
+		t.Errorf("description = %q with source %v, want neither", receipt.Description, receipt.Sources[fieldDescription])
+	}
+
+	expected := "amount=Generic.amount[0] (medium), recipient=Generic.recipient[0] (medium)"
+	if result := receipt.describeSources(); result != expected {
+		t.Errorf("describeSources() = %q, want %q", result, expected)
+	}
+}
+
+func TestFormatReceiptConfidenceMarkers(t *testing.T) {
+	receipt := extractReceipt("ALICI: Test User\nÖdenen 250,75 TL")
+	if receipt == nil {
+		t.Fatal("extractReceipt() = nil, want receipt")
+	}
+
+	if result := formatReceipt(receipt, &Configuration{}); strings.Contains(result, "⚠️") {
+		t.Errorf("formatReceipt() = %q, want no markers when disabled", result)
+	}
+
+	expected := "**Alıcı**: Test User\n**İşlem Tutarı**: 250,75 TL ⚠️"
+	if result := formatReceipt(receipt, &Configuration{FlagLowConfidenceFields: true}); result != expected {
+		t.Errorf("formatReceipt() = %q, want %q", result, expected)
+	}
+}
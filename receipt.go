@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Amount is an exact monetary value stored in minor units (kuruş, cents)
+type Amount int64
+
+// String formats the amount as a plain decimal with two fraction digits
+func (a Amount) String() string {
+	sign := ""
+	value := int64(a)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, value/100, value%100)
+}
+
+// MarshalJSON encodes the amount as a decimal string to avoid float rounding
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes an amount written by MarshalJSON
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	parsed, ok := parseAmount(text)
+	if !ok && text != "" {
+		return fmt.Errorf("invalid amount %q", text)
+	}
+	*a = parsed
+	return nil
+}
+
+// rateScale is the number of Rate units in one
+const rateScale = 1000000
+
+// Rate is an exact exchange rate stored in millionths
+type Rate int64
+
+// String formats the rate as a plain decimal with six fraction digits
+func (r Rate) String() string {
+	sign := ""
+	value := int64(r)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, value/rateScale, value%rateScale)
+}
+
+// MarshalJSON encodes the rate as a decimal string to avoid float rounding
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON decodes a rate written by MarshalJSON
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	parsed, ok := parseRate(text)
+	if !ok && text != "" {
+		return fmt.Errorf("invalid exchange rate %q", text)
+	}
+	*r = parsed
+	return nil
+}
+
+// Receipt is the structured result of parsing a bank receipt. Amount is the
+// transferred principal in Currency; Fee, Commission and Tax (BSMV) are the
+// charges on top of it and Total what the bank debited in all. The charges
+// and total are in ChargeCurrency when it is set, and TotalMismatch flags a
+// Total that is not the sum of the others. For foreign currency transactions
+// LocalAmount and ExchangeRate hold the lira equivalent and the rate it was
+// converted at. Sources records the rule and confidence of every extracted
+// field for debugging; it is not stored.
+type Receipt struct {
+	Bank            string                 `json:"bank,omitempty"`
+	BankConfidence  float64                `json:"bank_confidence,omitempty"`
+	Type            TransactionType        `json:"type,omitempty"`
+	Sender          string                 `json:"sender,omitempty"`
+	SenderIBAN      string                 `json:"sender_iban,omitempty"`
+	Recipient       string                 `json:"recipient,omitempty"`
+	RecipientIBAN   string                 `json:"recipient_iban,omitempty"`
+	Amount          Amount                 `json:"amount"`
+	AmountText      string                 `json:"amount_text,omitempty"`
+	Currency        string                 `json:"currency,omitempty"`
+	LocalAmount     Amount                 `json:"local_amount,omitempty"`
+	ExchangeRate    Rate                   `json:"exchange_rate,omitempty"`
+	Date            time.Time              `json:"date"`
+	DateText        string                 `json:"date_text,omitempty"`
+	Description     string                 `json:"description,omitempty"`
+	ReferenceNumber string                 `json:"reference_number,omitempty"`
+	Fee             Amount                 `json:"fee"`
+	Commission      Amount                 `json:"commission,omitempty"`
+	Tax             Amount                 `json:"tax,omitempty"`
+	Total           Amount                 `json:"total,omitempty"`
+	ChargeCurrency  string                 `json:"charge_currency,omitempty"`
+	TotalMismatch   bool                   `json:"total_mismatch,omitempty"`
+	Sources         map[string]FieldSource `json:"-"`
+}
+
+// IsEmpty reports whether no meaningful data was extracted. A date alone is
+// not meaningful, as most documents carry one.
+func (r *Receipt) IsEmpty() bool {
+	return r.Recipient == "" && r.Sender == "" && r.Description == "" && r.AmountText == ""
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAmountJSON(t *testing.T) {
+	data, err := json.Marshal(Amount(150050))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if string(data) != `"1500.50"` {
+		t.Errorf("json.Marshal() = %s, want %q", data, "1500.50")
+	}
+
+	var decoded Amount
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded != 150050 {
+		t.Errorf("json.Unmarshal() = %v, want %v", decoded, Amount(150050))
+	}
+}
+
+func TestExtractReceipt(t *testing.T) {
+	input := "HALKBANK EFT DEKONTU\nGÖNDEREN : İbrahim Yıldırım\nALICI : Medikal Cihazlar Ltd.\n" +
+		"İŞLEM TUTARI (TL) : 25,000.00\nAÇIKLAMA : Tıbbi cihaz alımı\nİŞLEM TARİHİ : 30.07.2025 16:45:12"
+
+	receipt := extractReceipt(input)
+	if receipt == nil {
+		t.Fatal("extractReceipt() = nil, want receipt")
+	}
+	if receipt.Bank != "HalkBank" {
+		t.Errorf("Bank = %q, want %q", receipt.Bank, "HalkBank")
+	}
+	if receipt.Sender != "İbrahim Yıldırım" || receipt.Recipient != "Medikal Cihazlar Ltd." {
+		t.Errorf("Sender, Recipient = %q, %q", receipt.Sender, receipt.Recipient)
+	}
+	if receipt.Amount != 2500000 || receipt.Currency != "TRY" {
+		t.Errorf("Amount = %v %s, want 25000.00 TRY", receipt.Amount, receipt.Currency)
+	}
+	if !receipt.Date.Equal(time.Date(2025, 7, 30, 16, 45, 12, 0, istanbul)) {
+		t.Errorf("Date = %v", receipt.Date)
+	}
+
+	if extractReceipt("Some random text without proper fields") != nil {
+		t.Error("extractReceipt() of unrelated text should be nil")
+	}
+}
+
+func TestExtractPartiesAfterAccountLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		sender    string
+		recipient string
+	}{
+		{
+			name: "IBAN and bank lines first",
+			input: "GÖNDEREN IBAN : TR260001500000001234567890\nGÖNDEREN : Ali\nALICI BANKA : GARANTİ\nALICI : Veli\n" +
+				"İŞLEM TUTARI : 100,00 TL",
+			sender:    "Ali",
+			recipient: "Veli",
+		},
+		{
+			name: "VakıfBank IBAN above the name",
+			input: "VAKIFBANK\nGÖNDEREN IBAN : TR260001500000001234567890\nGÖNDEREN AD SOYAD/UNVAN : MEHMET YILDIZ\n" +
+				"ALICI IBAN : TR970006700000007766554433\nALICI AD SOYAD/UNVAN : AYŞE KARA\nİŞLEM TUTARI : 3.250,00 TL",
+			sender:    "MEHMET YILDIZ",
+			recipient: "AYŞE KARA",
+		},
+		{
+			name: "account and branch lines first",
+			input: "GÖNDEREN HESAP NO : 12345678\nGÖNDEREN ŞUBESİ : KADIKÖY\nGÖNDEREN : Ali\nALICI BANKASI : AKBANK\n" +
+				"ALICI ŞUBE : MERKEZ\nALICI : Veli\nİŞLEM TUTARI : 100,00 TL",
+			sender:    "Ali",
+			recipient: "Veli",
+		},
+		{
+			name:      "names starting like a label",
+			input:     "GÖNDEREN : Hesapçı Ltd.\nALICI : Bankacılar Derneği\nİŞLEM TUTARI : 100,00 TL",
+			sender:    "Hesapçı Ltd.",
+			recipient: "Bankacılar Derneği",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := extractReceipt(tt.input)
+			if receipt == nil {
+				t.Fatal("extractReceipt() = nil, want receipt")
+			}
+			if receipt.Sender != tt.sender || receipt.Recipient != tt.recipient {
+				t.Errorf("Sender, Recipient = %q, %q, want %q, %q", receipt.Sender, receipt.Recipient, tt.sender, tt.recipient)
+			}
+		})
+	}
+}
+
+func TestExtractForeignCurrencyReceipt(t *testing.T) {
+	input := "DÖVİZ TRANSFER DEKONTU\nGÖNDEREN : Ahmet Kaya\nALICI : Global Trade LLC\n" +
+		"İŞLEM TUTARI : 1.000,00 USD\nTL KARŞILIĞI : 32.456,70 TL\nDÖVİZ KURU : 32,4567\nAÇIKLAMA : Fatura ödemesi"
+
+	receipt := extractReceipt(input)
+	if receipt == nil {
+		t.Fatal("extractReceipt() = nil, want receipt")
+	}
+	if receipt.Amount != 100000 || receipt.Currency != "USD" {
+		t.Errorf("amount = %v %s, want 1000.00 USD", receipt.Amount, receipt.Currency)
+	}
+	if receipt.LocalAmount != 3245670 || receipt.ExchangeRate != 32456700 {
+		t.Errorf("local amount = %v, rate = %v, want 32456.70 at 32.4567", receipt.LocalAmount, receipt.ExchangeRate)
+	}
+
+	expected := "**Açıklama**: Fatura ödemesi\n**Alıcı**: Global Trade LLC\n**Gönderen**: Ahmet Kaya\n" +
+		"**İşlem Tutarı**: 1.000,00 USD\n**TL Karşılığı**: 32.456,70 TL\n**Kur**: 1 USD = 32,4567 TL"
+	if result := formatReceipt(receipt, &Configuration{}); result != expected {
+		t.Errorf("formatReceipt() = %q, want %q", result, expected)
+	}
+
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded Receipt
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.ExchangeRate != receipt.ExchangeRate || decoded.LocalAmount != receipt.LocalAmount {
+		t.Errorf("json round trip = %v, %v, want %v, %v", decoded.LocalAmount, decoded.ExchangeRate, receipt.LocalAmount, receipt.ExchangeRate)
+	}
+}
+
+func TestExtractGoldReceipt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		amount   Amount
+		currency string
+		rendered string
+	}{
+		{
+			name:     "three fraction digits",
+			input:    "ALTIN ALIŞ DEKONTU\nALICI : Ayşe Kara\nALTIN ALIŞ TUTARI : 2,500 GR",
+			amount:   250,
+			currency: "XAU",
+			rendered: "2,50 gr altın",
+		},
+		{
+			name:     "unrepresentable fraction",
+			input:    "ALTIN ALIŞ DEKONTU\nALICI : Ayşe Kara\nALTIN ALIŞ TUTARI : 2,125 GR",
+			rendered: "2,125",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := extractReceipt(tt.input)
+			if receipt == nil {
+				t.Fatal("extractReceipt() = nil, want receipt")
+			}
+			if receipt.Amount != tt.amount || receipt.Currency != tt.currency {
+				t.Errorf("amount = %v %q, want %v %q", receipt.Amount, receipt.Currency, tt.amount, tt.currency)
+			}
+			if result := formatAmount(receipt, &Configuration{}); result != tt.rendered {
+				t.Errorf("formatAmount() = %q, want %q", result, tt.rendered)
+			}
+		})
+	}
+}
+
+func TestExtractLiraReceiptHasNoExchangeRate(t *testing.T) {
+	receipt := extractReceipt("ALICI: Test User\nTUTAR: 1.500,00 TL\nKUR: 1,0000")
+	if receipt == nil || receipt.Currency != "TRY" || receipt.ExchangeRate != 0 || receipt.LocalAmount != 0 {
+		t.Errorf("extractReceipt() = %+v, want a lira receipt without conversion", receipt)
+	}
+}
+
+func TestExtractReferenceNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "HalkBank transaction reference",
+			input:    "HALKBANK\nALICI : Test User\nİŞLEM TUTARI (TL) : 100,00\nİŞLEM REFERANS NO : HB2025073001",
+			expected: "HB2025073001",
+		},
+		{
+			name:     "Kuveyt Türk query number",
+			input:    "KUVEYT TÜRK\nGönderen Kişi: Fatma Yıldız\nAlıcı: Test User\nTutar: 100,00\nSorgu Numarası: 1234567890",
+			expected: "1234567890",
+		},
+		{
+			name:     "Yapı Kredi receipt number",
+			input:    "YAPI KREDİ\nALICI ADI: Test User\nGİDEN EFT TUTARI: 100,00\nDekont No: yk-2025/0001",
+			expected: "YK-2025/0001",
+		},
+		{
+			name:     "VakıfBank reference number",
+			input:    "VAKIFBANK\nALICI AD SOYAD/UNVAN: Test User\nİŞLEM TUTARI: 100,00\nREFERANS NO: 00123456",
+			expected: "00123456",
+		},
+		{
+			name:     "generic transaction reference",
+			input:    "ALICI: Test User\nTUTAR: 100,00 TL\nİşlem Referansı: GRN998877",
+			expected: "GRN998877",
+		},
+		{
+			name:     "reference preferred over receipt number",
+			input:    "ALICI: Test User\nDEKONT NO: 55\nTUTAR: 100,00 TL\nREFERANS NO: 7788",
+			expected: "7788",
+		},
+		{
+			name:     "label without a number",
+			input:    "ALICI: Test User\nTUTAR: 100,00 TL\nDekont No: -",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := extractReceipt(tt.input)
+			if receipt == nil {
+				t.Fatal("extractReceipt() = nil, want receipt")
+			}
+			if receipt.ReferenceNumber != tt.expected {
+				t.Errorf("ReferenceNumber = %q, want %q", receipt.ReferenceNumber, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractCharges(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		amount     Amount
+		fee        Amount
+		commission Amount
+		tax        Amount
+		total      Amount
+		mismatch   bool
+	}{
+		{
+			name: "consistent charges",
+			input: "ALICI : Test User\nTOPLAM TUTAR : 1.015,75 TL\nMASRAF TUTARI : 10,00 TL\nBSMV : 0,75 TL\n" +
+				"KOMİSYON : 5,00 TL\nİŞLEM TUTARI : 1.000,00 TL",
+			amount: 100000, fee: 1000, commission: 500, tax: 75, total: 101575,
+		},
+		{
+			name:   "total does not add up",
+			input:  "ALICI : Test User\nEFT TUTARI : 500,00 TL\nEFT ÜCRETİ : 4,50 TL\nTOPLAM TUTAR : 600,00 TL",
+			amount: 50000, fee: 450, total: 60000, mismatch: true,
+		},
+		{
+			name:   "fee printed before the amount on the same line",
+			input:  "ALICI : Test User\nMASRAF: 2,00 TL  TUTAR: 250,00 TL",
+			amount: 25000, fee: 200,
+		},
+		{
+			name:   "only a total",
+			input:  "ALICI : Test User\nTOPLAM TUTAR : 300,00 TL",
+			amount: 30000, total: 30000,
+		},
+		{
+			name: "lira fees on a foreign transfer are not checked",
+			input: "ALICI : Global Trade LLC\nİŞLEM TUTARI : 1.000,00 USD\nMASRAF : 250,00 TL\nBSMV : 12,50 TL\n" +
+				"TOPLAM TUTAR : 262,50 TL",
+			amount: 100000, fee: 25000, tax: 1250, total: 26250,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := extractReceipt(tt.input)
+			if r == nil {
+				t.Fatal("extractReceipt() = nil, want receipt")
+			}
+			if r.Amount != tt.amount || r.Fee != tt.fee || r.Commission != tt.commission || r.Tax != tt.tax || r.Total != tt.total {
+				t.Errorf("amount, fee, commission, tax, total = %v, %v, %v, %v, %v, want %v, %v, %v, %v, %v",
+					r.Amount, r.Fee, r.Commission, r.Tax, r.Total, tt.amount, tt.fee, tt.commission, tt.tax, tt.total)
+			}
+			if r.TotalMismatch != tt.mismatch {
+				t.Errorf("TotalMismatch = %v, want %v", r.TotalMismatch, tt.mismatch)
+			}
+		})
+	}
+}
+
+func TestFormatReceiptCharges(t *testing.T) {
+	receipt := &Receipt{Recipient: "Test User", Amount: 50000, AmountText: "500,00", Currency: "TRY",
+		Fee: 450, Tax: 23, Total: 60000, TotalMismatch: true}
+	expected := "**Alıcı**: Test User\n**İşlem Tutarı**: 500,00 TL\n**Masraf**: 4,50 TL\n**BSMV**: 0,23 TL\n" +
+		"**Toplam Tutar**: 600,00 TL ⚠️ *Tutar ve masraflar toplamı tutmuyor*"
+	if result := formatReceipt(receipt, &Configuration{}); result != expected {
+		t.Errorf("formatReceipt() = %q, want %q", result, expected)
+	}
+
+	foreign := &Receipt{Amount: 100000, AmountText: "1.000,00", Currency: "USD", Fee: 25000, ChargeCurrency: "TRY"}
+	if result := formatReceipt(foreign, &Configuration{}); !strings.Contains(result, "**Masraf**: 250,00 TL") {
+		t.Errorf("formatReceipt() = %q, want the fee in lira", result)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import "strings"
+
+// segmentReceipts splits the pages of a document into the texts of the
+// individual receipts it contains. Documents repeating their first line as a
+// header are split at every repetition, otherwise every page is tried as a
+// receipt of its own. A split is only accepted when every segment passes
+// isStandaloneReceipt, so a single receipt spanning several pages stays in
+// one piece.
+func (r *parserRegistry) segmentReceipts(pages []string) []string {
+	text := joinPages(pages)
+
+	if segments := splitOnRepeatedHeader(text); r.isReceiptSplit(segments) {
+		return segments
+	}
+
+	var nonEmpty []string
+	for _, page := range pages {
+		if strings.TrimSpace(page) != "" {
+			nonEmpty = append(nonEmpty, page)
+		}
+	}
+	if r.isReceiptSplit(nonEmpty) {
+		return nonEmpty
+	}
+
+	return []string{text}
+}
+
+// splitOnRepeatedHeader splits text before every line equal to its first
+// non-empty line. It returns nil when the header does not repeat.
+func splitOnRepeatedHeader(text string) []string {
+	lines := strings.Split(text, "\n")
+
+	header := ""
+	for _, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			header = trimmed
+			break
+		}
+	}
+	if header == "" {
+		return nil
+	}
+
+	var segments []string
+	var current []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == header && len(current) > 0 {
+			segments = append(segments, strings.Join(current, "\n"))
+			current = nil
+		}
+		current = append(current, line)
+	}
+	segments = append(segments, strings.Join(current, "\n"))
+
+	if len(segments) < 2 {
+		return nil
+	}
+	return segments
+}
+
+// isReceiptSplit reports whether segments holds at least two texts that each
+// contain a receipt of its own
+func (r *parserRegistry) isReceiptSplit(segments []string) bool {
+	if len(segments) < 2 {
+		return false
+	}
+	for _, segment := range segments {
+		if !isStandaloneReceipt(r.extractReceipt(segment)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isStandaloneReceipt reports whether a receipt parsed from part of a document
+// can stand on its own: it needs an amount read by a labelled rule, not the
+// fallback to a charge such as the total, and a party, reference or date.
+// The last page of a single receipt often only lists its charges.
+func isStandaloneReceipt(receipt *Receipt) bool {
+	if receipt == nil || receipt.AmountText == "" || receipt.Sources[fieldAmount].Confidence < confidenceMedium {
+		return false
+	}
+	return receipt.Recipient != "" || receipt.Sender != "" || receipt.ReferenceNumber != "" || !receipt.Date.IsZero()
+}
+
+// extractReceipts parses every receipt found in the pages of a document
+func (r *parserRegistry) extractReceipts(pages []string) []*Receipt {
+	var receipts []*Receipt
+	for _, segment := range r.segmentReceipts(pages) {
+		if receipt := r.extractReceipt(segment); receipt != nil {
+			receipts = append(receipts, receipt)
+		}
+	}
+	return receipts
+}
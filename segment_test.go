@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegmentReceipts(t *testing.T) {
+	tests := []struct {
+		name     string
+		pages    []string
+		expected int
+	}{
+		{
+			name:     "single page receipt",
+			pages:    []string{"ALICI: Test User\nTUTAR: 100.00 TL"},
+			expected: 1,
+		},
+		{
+			name:     "one receipt per page",
+			pages:    []string{"ALICI: First User\nTUTAR: 100.00 TL", "", "ALICI: Second User\nTUTAR: 200.00 TL"},
+			expected: 2,
+		},
+		{
+			name:     "amount on second page",
+			pages:    []string{"ALICI: Test User\nAÇIKLAMA: Rent", "TUTAR: 100.00 TL"},
+			expected: 1,
+		},
+		{
+			name: "total on second page",
+			pages: []string{"ALICI: Test User\nİŞLEM TARİHİ: 14.08.2025\nİŞLEM TUTARI: 48.500,00 TL",
+				"MASRAF: 9,25 TL\nTOPLAM TUTAR: 48.509,25 TL"},
+			expected: 1,
+		},
+		{
+			name:     "amounts without parties",
+			pages:    []string{"TUTAR: 100.00 TL", "TUTAR: 200.00 TL"},
+			expected: 1,
+		},
+		{
+			name: "repeated header on one page",
+			pages: []string{"HALKBANK EFT DEKONTU\nALICI : First User\nİŞLEM TUTARI (TL) : 100.00\n" +
+				"HALKBANK EFT DEKONTU\nALICI : Second User\nİŞLEM TUTARI (TL) : 200.00\n" +
+				"HALKBANK EFT DEKONTU\nALICI : Third User\nİŞLEM TUTARI (TL) : 300.00"},
+			expected: 3,
+		},
+		{
+			name:     "repeated bank name of a single receipt",
+			pages:    []string{"HALKBANK\nALICI : Test User", "HALKBANK\nİŞLEM TUTARI (TL) : 100.00"},
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := defaultRegistry.segmentReceipts(tt.pages)
+			if len(result) != tt.expected {
+				t.Errorf("segmentReceipts() returned %d segments, want %d: %q", len(result), tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestExtractReceipts(t *testing.T) {
+	pages := []string{
+		"ALICI: First User\nAÇIKLAMA: Rent\nTUTAR: 100.00 TL",
+		"ALICI: Second User\nAÇIKLAMA: Invoice\nTUTAR: 200.00 TL",
+	}
+
+	receipts := defaultRegistry.extractReceipts(pages)
+	if len(receipts) != 2 {
+		t.Fatalf("extractReceipts() returned %d receipts, want 2", len(receipts))
+	}
+	if receipts[0].Recipient != "First User" || receipts[1].Recipient != "Second User" {
+		t.Errorf("extractReceipts() recipients = %q, %q", receipts[0].Recipient, receipts[1].Recipient)
+	}
+}
+
+func TestFormatReceiptTable(t *testing.T) {
+	config := &Configuration{BankConfidenceThreshold: 60}
+	receipts := []*Receipt{
+		{Bank: "HalkBank", BankConfidence: 0.8, Type: typeHavale, Recipient: "First | User", Amount: 10000, AmountText: "100.00", Currency: "TRY"},
+		{Recipient: "Second User", Description: "Invoice", Amount: 20000, AmountText: "200.00", Currency: "TRY", DateText: "15.07.2025"},
+	}
+
+	expected := "| # | Banka | Tür | Tarih | Gönderen | Alıcı | Açıklama | Tutar |\n" +
+		"|---|---|---|---|---|---|---|---:|\n" +
+		"| 1 | HalkBank | Havale |  |  | First \\| User |  | 100,00 TL |\n" +
+		"| 2 | ⚠️ |  | 15.07.2025 |  | Second User | Invoice | 200,00 TL |"
+
+	result := formatReceiptTable(receipts, config)
+	if result != expected {
+		t.Errorf("formatReceiptTable() = %q, want %q", result, expected)
+	}
+
+	message := formatMessage(receipts, &Configuration{BankConfidenceThreshold: 60, HideCredits: true})
+	if !strings.HasPrefix(message, "**2 dekont bulundu**\n\n| # |") {
+		t.Errorf("formatMessage() = %q, want table of 2 receipts", message)
+	}
+}
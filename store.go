@@ -0,0 +1,580 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin"
+)
+
+// KV store key layout. Transactions are stored under their ID, the
+// secondary indexes hold JSON lists of transaction IDs.
+const (
+	transactionKeyPrefix  = "tx_"
+	channelIndexPrefix    = "idx_channel_"
+	dayIndexPrefix        = "idx_day_" // days in Istanbul time
+	counterpartyIdxPrefix = "idx_party_"
+	referenceIndexPrefix  = "idx_ref_"
+	dayIndexLayout        = "20060102"
+	// Failed parses are stored under their file ID and indexed by channel
+	failureKeyPrefix   = "fail_"
+	failureIndexPrefix = "idx_fail_channel_"
+	// Webhook deliveries are stored under their ID. The pending index lists
+	// the deliveries still to be sent, the log index the latest deliveries,
+	// oldest first.
+	deliveryKeyPrefix   = "wh_"
+	deliveryPendingKey  = "idx_wh_pending"
+	deliveryLogKey      = "idx_wh_log"
+	maxLoggedDeliveries = 200
+
+	// indexUpdateRetries bounds the compare-and-set attempts of an index update
+	indexUpdateRetries = 5
+	// kvListPageSize is the page size used when scanning all keys
+	kvListPageSize = 200
+	// maxIndexedDays bounds the number of day indexes read for one query
+	maxIndexedDays = 366
+)
+
+// Transaction is a parsed receipt together with where it was posted
+type Transaction struct {
+	ID        string   `json:"id"`
+	FileID    string   `json:"file_id"`
+	FileName  string   `json:"file_name,omitempty"`
+	PostID    string   `json:"post_id"`
+	ChannelID string   `json:"channel_id"`
+	UserID    string   `json:"user_id"`
+	CreateAt  int64    `json:"create_at"`
+	Receipt   *Receipt `json:"receipt"`
+}
+
+// Time returns the transaction date printed on the receipt, or the time the
+// receipt was processed when the receipt has no date, in Istanbul time
+func (t *Transaction) Time() time.Time {
+	if t.Receipt != nil && !t.Receipt.Date.IsZero() {
+		return t.Receipt.Date.In(istanbul)
+	}
+	return time.UnixMilli(t.CreateAt).In(istanbul)
+}
+
+// ParseFailure is a PDF attachment no receipt could be parsed from
+type ParseFailure struct {
+	FileID    string `json:"file_id"`
+	FileName  string `json:"file_name"`
+	PostID    string `json:"post_id"`
+	ChannelID string `json:"channel_id"`
+	CreateAt  int64  `json:"create_at"`
+	Reason    string `json:"reason"`
+}
+
+// transactionID returns the ID of the index-th receipt of a file
+func transactionID(fileID string, index int) string {
+	return fmt.Sprintf("%s_%d", fileID, index)
+}
+
+// TransactionQuery filters stored transactions. Zero values match everything.
+type TransactionQuery struct {
+	ChannelID string
+	From      time.Time
+	To        time.Time
+	// Counterparty matches the sender or recipient, ignoring case and spacing
+	Counterparty string
+	// Reference matches the reference number, ignoring case and spacing
+	Reference string
+	Type      TransactionType
+	// Bank matches the detected bank name, ignoring case
+	Bank string
+	// MinAmount and MaxAmount bound the amount in its own currency
+	MinAmount Amount
+	MaxAmount Amount
+}
+
+// matches reports whether the transaction passes every filter of the query
+func (q TransactionQuery) matches(tx *Transaction) bool {
+	if q.ChannelID != "" && tx.ChannelID != q.ChannelID {
+		return false
+	}
+	date := tx.Time()
+	if !q.From.IsZero() && date.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && date.After(q.To) {
+		return false
+	}
+	if q.Type != "" && (tx.Receipt == nil || tx.Receipt.Type != q.Type) {
+		return false
+	}
+	if q.Bank != "" && (tx.Receipt == nil || !strings.EqualFold(tx.Receipt.Bank, q.Bank)) {
+		return false
+	}
+	if (q.MinAmount != 0 || q.MaxAmount != 0) && (tx.Receipt == nil ||
+		tx.Receipt.Amount < q.MinAmount || (q.MaxAmount != 0 && tx.Receipt.Amount > q.MaxAmount)) {
+		return false
+	}
+	if q.Reference != "" && (tx.Receipt == nil || tx.Receipt.ReferenceNumber != normalizeReference(q.Reference)) {
+		return false
+	}
+	if q.Counterparty != "" {
+		wanted := normalizeCounterparty(q.Counterparty)
+		if tx.Receipt == nil ||
+			(normalizeCounterparty(tx.Receipt.Sender) != wanted && normalizeCounterparty(tx.Receipt.Recipient) != wanted) {
+			return false
+		}
+	}
+	return true
+}
+
+// transactionStore persists transactions in the plugin KV store
+type transactionStore struct {
+	api plugin.API
+}
+
+// newTransactionStore creates a store backed by the plugin KV store
+func newTransactionStore(api plugin.API) *transactionStore {
+	return &transactionStore{api: api}
+}
+
+// Save stores the transaction and adds it to the channel, day, counterparty
+// and reference indexes. Saving a transaction again replaces it.
+func (s *transactionStore) Save(tx *Transaction) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	if appErr := s.api.KVSet(transactionKeyPrefix+tx.ID, data); appErr != nil {
+		return appErr
+	}
+
+	for _, key := range indexKeys(tx) {
+		if err := s.addToIndex(key, tx.ID); err != nil {
+			return fmt.Errorf("failed to update index %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Get returns the transaction with the given ID, or nil if it does not exist
+func (s *transactionStore) Get(id string) (*Transaction, error) {
+	data, appErr := s.api.KVGet(transactionKeyPrefix + id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var tx Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// List returns the transactions matching the query, newest first. The most
+// selective index available for the query is used to find candidates.
+func (s *transactionStore) List(query TransactionQuery) ([]*Transaction, error) {
+	ids, err := s.candidateIDs(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Transaction
+	for _, id := range ids {
+		tx, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if tx != nil && query.matches(tx) {
+			result = append(result, tx)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time().After(result[j].Time())
+	})
+	return result, nil
+}
+
+// SaveFailure stores a failed parse and adds it to the failure index of its
+// channel
+func (s *transactionStore) SaveFailure(failure *ParseFailure) error {
+	data, err := json.Marshal(failure)
+	if err != nil {
+		return err
+	}
+	if appErr := s.api.KVSet(failureKeyPrefix+failure.FileID, data); appErr != nil {
+		return appErr
+	}
+	return s.addToIndex(failureIndexPrefix+failure.ChannelID, failure.FileID)
+}
+
+// ListFailures returns the failed parses of the channel between from and to,
+// oldest first. Files that were parsed successfully since are left out.
+func (s *transactionStore) ListFailures(channelID string, from, to time.Time) ([]*ParseFailure, error) {
+	ids, err := s.readIndex(failureIndexPrefix + channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*ParseFailure
+	for _, id := range ids {
+		data, appErr := s.api.KVGet(failureKeyPrefix + id)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if data == nil {
+			continue
+		}
+		var failure ParseFailure
+		if err := json.Unmarshal(data, &failure); err != nil {
+			return nil, err
+		}
+		created := time.UnixMilli(failure.CreateAt)
+		if created.Before(from) || created.After(to) {
+			continue
+		}
+		if tx, err := s.Get(transactionID(id, 0)); err != nil {
+			return nil, err
+		} else if tx != nil {
+			continue
+		}
+		result = append(result, &failure)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreateAt < result[j].CreateAt
+	})
+	return result, nil
+}
+
+// QueueDelivery stores a new webhook delivery as pending and adds it to the
+// delivery log, dropping the oldest deliveries beyond maxLoggedDeliveries
+// that are no longer pending
+func (s *transactionStore) QueueDelivery(delivery *WebhookDelivery) error {
+	if err := s.SaveDelivery(delivery); err != nil {
+		return err
+	}
+	if err := s.addToIndex(deliveryPendingKey, delivery.ID); err != nil {
+		return err
+	}
+	pending, err := s.readIndex(deliveryPendingKey)
+	if err != nil {
+		return err
+	}
+	isPending := map[string]bool{}
+	for _, id := range pending {
+		isPending[id] = true
+	}
+
+	var dropped []string
+	err = s.updateIndex(deliveryLogKey, func(ids []string) ([]string, bool) {
+		ids = append(ids, delivery.ID)
+		dropped = nil
+		kept := make([]string, 0, len(ids))
+		for i, id := range ids {
+			if len(ids)-i > maxLoggedDeliveries && !isPending[id] {
+				dropped = append(dropped, id)
+				continue
+			}
+			kept = append(kept, id)
+		}
+		return kept, true
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range dropped {
+		if appErr := s.api.KVDelete(deliveryKeyPrefix + id); appErr != nil {
+			return appErr
+		}
+	}
+	return nil
+}
+
+// SaveDelivery stores the state of a webhook delivery and removes it from
+// the pending index once it is no longer pending
+func (s *transactionStore) SaveDelivery(delivery *WebhookDelivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	if appErr := s.api.KVSet(deliveryKeyPrefix+delivery.ID, data); appErr != nil {
+		return appErr
+	}
+	if delivery.Status != deliveryPending {
+		return s.removeFromIndex(deliveryPendingKey, delivery.ID)
+	}
+	return nil
+}
+
+// GetDelivery returns a webhook delivery, or nil if it does not exist
+func (s *transactionStore) GetDelivery(id string) (*WebhookDelivery, error) {
+	data, appErr := s.api.KVGet(deliveryKeyPrefix + id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var delivery WebhookDelivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// PendingDeliveries returns the webhook deliveries still to be sent, oldest
+// first
+func (s *transactionStore) PendingDeliveries() ([]*WebhookDelivery, error) {
+	return s.deliveries(deliveryPendingKey)
+}
+
+// ListDeliveries returns the delivery log, newest first
+func (s *transactionStore) ListDeliveries() ([]*WebhookDelivery, error) {
+	deliveries, err := s.deliveries(deliveryLogKey)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(deliveries)-1; i < j; i, j = i+1, j-1 {
+		deliveries[i], deliveries[j] = deliveries[j], deliveries[i]
+	}
+	return deliveries, nil
+}
+
+// deliveries reads the webhook deliveries of an index
+func (s *transactionStore) deliveries(indexKey string) ([]*WebhookDelivery, error) {
+	ids, err := s.readIndex(indexKey)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*WebhookDelivery, 0, len(ids))
+	for _, id := range ids {
+		delivery, err := s.GetDelivery(id)
+		if err != nil {
+			return nil, err
+		}
+		if delivery != nil {
+			result = append(result, delivery)
+		}
+	}
+	return result, nil
+}
+
+// candidateIDs returns the IDs of the transactions that may match query
+func (s *transactionStore) candidateIDs(query TransactionQuery) ([]string, error) {
+	switch {
+	case query.Reference != "":
+		return s.readIndex(referenceIndexKey(query.Reference))
+	case query.Counterparty != "":
+		return s.readIndex(counterpartyIndexKey(query.Counterparty))
+	case query.ChannelID != "":
+		return s.readIndex(channelIndexPrefix + query.ChannelID)
+	case !query.From.IsZero() && !query.To.IsZero() && query.To.Sub(query.From) <= maxIndexedDays*24*time.Hour:
+		var ids []string
+		for day := truncateDay(query.From.In(istanbul)); !day.After(query.To); day = day.AddDate(0, 0, 1) {
+			dayIDs, err := s.readIndex(dayIndexPrefix + day.Format(dayIndexLayout))
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, dayIDs...)
+		}
+		return ids, nil
+	default:
+		return s.allIDs()
+	}
+}
+
+// allIDs scans the KV store for every stored transaction
+func (s *transactionStore) allIDs() ([]string, error) {
+	var ids []string
+	for page := 0; ; page++ {
+		keys, appErr := s.api.KVList(page, kvListPageSize)
+		if appErr != nil {
+			return nil, appErr
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, transactionKeyPrefix) {
+				ids = append(ids, strings.TrimPrefix(key, transactionKeyPrefix))
+			}
+		}
+		if len(keys) < kvListPageSize {
+			return ids, nil
+		}
+	}
+}
+
+// readIndex returns the transaction IDs stored under an index key
+func (s *transactionStore) readIndex(key string) ([]string, error) {
+	data, appErr := s.api.KVGet(key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// addToIndex appends id to the index list under key
+func (s *transactionStore) addToIndex(key, id string) error {
+	return s.updateIndex(key, func(ids []string) ([]string, bool) {
+		for _, existing := range ids {
+			if existing == id {
+				return nil, false
+			}
+		}
+		return append(ids, id), true
+	})
+}
+
+// removeFromIndex removes id from the index list under key
+func (s *transactionStore) removeFromIndex(key, id string) error {
+	return s.updateIndex(key, func(ids []string) ([]string, bool) {
+		for i, existing := range ids {
+			if existing == id {
+				return append(ids[:i:i], ids[i+1:]...), true
+			}
+		}
+		return nil, false
+	})
+}
+
+// updateIndex replaces the index list under key with the list returned by
+// update, unless update reports no change. Concurrent updates are resolved
+// with compare-and-set.
+func (s *transactionStore) updateIndex(key string, update func(ids []string) ([]string, bool)) error {
+	for attempt := 0; attempt < indexUpdateRetries; attempt++ {
+		oldData, appErr := s.api.KVGet(key)
+		if appErr != nil {
+			return appErr
+		}
+
+		var ids []string
+		if oldData != nil {
+			if err := json.Unmarshal(oldData, &ids); err != nil {
+				return err
+			}
+		}
+		ids, changed := update(ids)
+		if !changed {
+			return nil
+		}
+
+		newData, err := json.Marshal(ids)
+		if err != nil {
+			return err
+		}
+		saved, appErr := s.api.KVCompareAndSet(key, oldData, newData)
+		if appErr != nil {
+			return appErr
+		}
+		if saved {
+			return nil
+		}
+	}
+	return fmt.Errorf("gave up after %d concurrent updates", indexUpdateRetries)
+}
+
+// indexKeys returns the secondary index keys a transaction belongs to
+func indexKeys(tx *Transaction) []string {
+	keys := []string{
+		channelIndexPrefix + tx.ChannelID,
+		dayIndexPrefix + tx.Time().Format(dayIndexLayout),
+	}
+	if tx.Receipt != nil {
+		for _, party := range []string{tx.Receipt.Sender, tx.Receipt.Recipient} {
+			if normalizeCounterparty(party) != "" {
+				keys = append(keys, counterpartyIndexKey(party))
+			}
+		}
+		if tx.Receipt.ReferenceNumber != "" {
+			keys = append(keys, referenceIndexKey(tx.Receipt.ReferenceNumber))
+		}
+	}
+	return keys
+}
+
+// referenceIndexKey hashes the normalized reference number so arbitrary
+// references fit into a KV key
+func referenceIndexKey(reference string) string {
+	return referenceIndexPrefix + hashHex(normalizeReference(reference))
+}
+
+// counterpartyIndexKey hashes the normalized name so arbitrary names fit
+// into a KV key
+func counterpartyIndexKey(name string) string {
+	return counterpartyIdxPrefix + hashHex(normalizeCounterparty(name))
+}
+
+// normalizeCounterparty folds case the Turkish way and collapses spacing so
+// "ABC  Şirketi" and "abc şirketi" are the same counterparty
+func normalizeCounterparty(name string) string {
+	return strings.Join(strings.Fields(strings.ToLowerSpecial(unicode.TurkishCase, name)), " ")
+}
+
+// truncateDay returns midnight of the day of t
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// totalsByCurrency sums the transaction amounts per currency
+func totalsByCurrency(transactions []*Transaction) map[string]Amount {
+	receipts := make([]*Receipt, 0, len(transactions))
+	for _, tx := range transactions {
+		if tx.Receipt != nil {
+			receipts = append(receipts, tx.Receipt)
+		}
+	}
+	return receiptTotals(receipts)
+}
+
+// receiptTotals sums the receipt amounts per currency
+func receiptTotals(receipts []*Receipt) map[string]Amount {
+	totals := map[string]Amount{}
+	for _, receipt := range receipts {
+		if receipt.Currency != "" {
+			totals[receipt.Currency] += receipt.Amount
+		}
+	}
+	return totals
+}
+
+// saveTransactions persists the receipts parsed from a file of post. It
+// returns the transactions saved before any error that were not stored yet,
+// leaving out those of an earlier processing of the post.
+func (p *Plugin) saveTransactions(post *model.Post, fileInfo *model.FileInfo, receipts []*Receipt) ([]*Transaction, error) {
+	var created []*Transaction
+	createAt := model.GetMillis()
+	for i, receipt := range receipts {
+		tx := &Transaction{
+			ID:        transactionID(fileInfo.Id, i),
+			FileID:    fileInfo.Id,
+			FileName:  fileInfo.Name,
+			PostID:    post.Id,
+			ChannelID: post.ChannelId,
+			UserID:    post.UserId,
+			CreateAt:  createAt,
+			Receipt:   receipt,
+		}
+		existing, err := p.store.Get(tx.ID)
+		if err != nil {
+			return created, err
+		}
+		if err := p.store.Save(tx); err != nil {
+			return created, err
+		}
+		if existing == nil {
+			created = append(created, tx)
+		}
+	}
+	return created, nil
+}
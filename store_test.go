@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+)
+
+// memoryKV backs the KV methods of a plugintest.API with a map
+type memoryKV struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+// newKVTestAPI returns a mock API whose KV store lives in memory. Log calls
+// are accepted and ignored.
+func newKVTestAPI() (*plugintest.API, *memoryKV) {
+	kv := &memoryKV{data: map[string][]byte{}}
+	api := &plugintest.API{}
+
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte { return kv.get(key) },
+		func(string) *model.AppError { return nil }).Maybe()
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(
+		func(key string, value []byte) *model.AppError {
+			kv.set(key, value)
+			return nil
+		}).Maybe()
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(
+		func(key string) *model.AppError {
+			kv.set(key, nil)
+			return nil
+		}).Maybe()
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(
+		func(key string, oldValue, newValue []byte) bool { return kv.compareAndSet(key, oldValue, newValue) },
+		func(string, []byte, []byte) *model.AppError { return nil }).Maybe()
+	api.On("KVCompareAndDelete", mock.AnythingOfType("string"), mock.Anything).Return(
+		func(key string, oldValue []byte) bool { return kv.compareAndSet(key, oldValue, nil) },
+		func(string, []byte) *model.AppError { return nil }).Maybe()
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			if options.Atomic {
+				return kv.compareAndSet(key, options.OldValue, value)
+			}
+			kv.set(key, value)
+			return true
+		},
+		func(string, []byte, model.PluginKVSetOptions) *model.AppError { return nil }).Maybe()
+	api.On("KVList", mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(
+		func(page, perPage int) []string { return kv.list(page, perPage) },
+		func(int, int) *model.AppError { return nil }).Maybe()
+
+	for _, level := range []string{"LogDebug", "LogInfo", "LogWarn", "LogError"} {
+		for args := 1; args <= 15; args += 2 {
+			anything := make([]interface{}, args)
+			for i := range anything {
+				anything[i] = mock.Anything
+			}
+			api.On(level, anything...).Maybe()
+		}
+	}
+
+	return api, kv
+}
+
+func (kv *memoryKV) get(key string) []byte {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	return kv.data[key]
+}
+
+func (kv *memoryKV) set(key string, value []byte) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	if value == nil {
+		delete(kv.data, key)
+		return
+	}
+	kv.data[key] = value
+}
+
+func (kv *memoryKV) compareAndSet(key string, oldValue, newValue []byte) bool {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	current, exists := kv.data[key]
+	if (oldValue == nil && exists) || (oldValue != nil && !bytes.Equal(current, oldValue)) {
+		return false
+	}
+	if newValue == nil {
+		delete(kv.data, key)
+	} else {
+		kv.data[key] = newValue
+	}
+	return true
+}
+
+func (kv *memoryKV) list(page, perPage int) []string {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	keys := make([]string, 0, len(kv.data))
+	for key := range kv.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	start := page * perPage
+	if start >= len(keys) {
+		return []string{}
+	}
+	end := start + perPage
+	if end > len(keys) {
+		end = len(keys)
+	}
+	return keys[start:end]
+}
+
+func TestTransactionStore(t *testing.T) {
+	api, _ := newKVTestAPI()
+	store := newTransactionStore(api)
+
+	transactions := []*Transaction{
+		{ID: "file1_0", FileID: "file1", ChannelID: "finance", Receipt: &Receipt{
+			Bank: "VakıfBank", Sender: "Ahmet Kaya", Recipient: "ABC Şirketi", Amount: 10000, Currency: "TRY", Type: typeEFT,
+			Date: time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)}},
+		{ID: "file2_0", FileID: "file2", ChannelID: "finance", Receipt: &Receipt{
+			Sender: "Mehmet Yılmaz", Recipient: "abc  şirketi", Amount: 25050, Currency: "TRY", ReferenceNumber: "SRG-4711",
+			Date: time.Date(2025, 7, 15, 10, 0, 0, 0, time.UTC)}},
+		{ID: "file3_0", FileID: "file3", ChannelID: "payments", Receipt: &Receipt{
+			Sender: "Ahmet Kaya", Recipient: "XYZ Ltd.", Amount: 5000, Currency: "TRY", Type: typeEFT,
+			Date: time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)}},
+	}
+	for _, tx := range transactions {
+		if err := store.Save(tx); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	// Saving again must not duplicate index entries
+	if err := store.Save(transactions[0]); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    TransactionQuery
+		expected []string
+	}{
+		{
+			name:     "all transactions newest first",
+			query:    TransactionQuery{},
+			expected: []string{"file3_0", "file2_0", "file1_0"},
+		},
+		{
+			name:     "by channel",
+			query:    TransactionQuery{ChannelID: "finance"},
+			expected: []string{"file2_0", "file1_0"},
+		},
+		{
+			name: "by date range",
+			query: TransactionQuery{
+				From: time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2025, 8, 31, 0, 0, 0, 0, time.UTC),
+			},
+			expected: []string{"file3_0", "file2_0"},
+		},
+		{
+			name:     "by counterparty ignoring case and spacing",
+			query:    TransactionQuery{Counterparty: "ABC ŞİRKETİ"},
+			expected: []string{"file2_0", "file1_0"},
+		},
+		{
+			name:     "by counterparty and channel",
+			query:    TransactionQuery{Counterparty: "Ahmet Kaya", ChannelID: "payments"},
+			expected: []string{"file3_0"},
+		},
+		{
+			name:     "by reference ignoring case and spacing",
+			query:    TransactionQuery{Reference: "srg - 4711"},
+			expected: []string{"file2_0"},
+		},
+		{
+			name:     "by transaction type",
+			query:    TransactionQuery{Type: typeEFT},
+			expected: []string{"file3_0", "file1_0"},
+		},
+		{
+			name:     "by bank ignoring case",
+			query:    TransactionQuery{Bank: "VAKıFBANK"},
+			expected: []string{"file1_0"},
+		},
+		{
+			name:     "by minimum amount",
+			query:    TransactionQuery{MinAmount: 10000},
+			expected: []string{"file2_0", "file1_0"},
+		},
+		{
+			name:     "by amount range",
+			query:    TransactionQuery{MinAmount: 5000, MaxAmount: 10000},
+			expected: []string{"file3_0", "file1_0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.List(tt.query)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			ids := make([]string, 0, len(result))
+			for _, tx := range result {
+				ids = append(ids, tx.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("List() = %v, want %v", ids, tt.expected)
+			}
+		})
+	}
+
+	all, _ := store.List(TransactionQuery{})
+	if totals := totalsByCurrency(all); totals["TRY"] != 40050 {
+		t.Errorf("totalsByCurrency() = %v, want TRY 400.50", totals)
+	}
+}
+
+func TestTransactionStoreGetMissing(t *testing.T) {
+	api, _ := newKVTestAPI()
+	tx, err := newTransactionStore(api).Get("missing")
+	if tx != nil || err != nil {
+		t.Errorf("Get() = %v, %v, want nil, nil", tx, err)
+	}
+}
+
+func TestDeliveryLog(t *testing.T) {
+	api, kv := newKVTestAPI()
+	store := newTransactionStore(api)
+
+	// The oldest delivery stays pending, the others are delivered
+	count := maxLoggedDeliveries + 2
+	for i := 0; i < count; i++ {
+		delivery := &WebhookDelivery{ID: fmt.Sprintf("delivery%03d", i), Status: deliveryPending}
+		if err := store.QueueDelivery(delivery); err != nil {
+			t.Fatalf("QueueDelivery() error = %v", err)
+		}
+		if i > 0 {
+			delivery.Status = deliveryDelivered
+			if err := store.SaveDelivery(delivery); err != nil {
+				t.Fatalf("SaveDelivery() error = %v", err)
+			}
+		}
+	}
+
+	deliveries, err := store.ListDeliveries()
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	if len(deliveries) != maxLoggedDeliveries+1 {
+		t.Fatalf("ListDeliveries() returned %d deliveries, want %d", len(deliveries), maxLoggedDeliveries+1)
+	}
+	if first, last := deliveries[0].ID, deliveries[len(deliveries)-1].ID; first != fmt.Sprintf("delivery%03d", count-1) || last != "delivery000" {
+		t.Errorf("ListDeliveries() = %s ... %s, want newest first and the pending delivery kept", first, last)
+	}
+	if kv.get(deliveryKeyPrefix+"delivery001") != nil {
+		t.Error("QueueDelivery() kept the record of a dropped delivery")
+	}
+
+	pending, err := store.PendingDeliveries()
+	if err != nil {
+		t.Fatalf("PendingDeliveries() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != "delivery000" {
+		t.Errorf("PendingDeliveries() = %+v, want delivery000 only", pending)
+	}
+}
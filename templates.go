@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// bankTemplate is an admin defined bank declared in the CustomBankTemplates
+// setting, such as
+//
+//	[{"name": "Örnek Bank", "keywords": ["ÖRNEK BANK", "ornekbank.com.tr"],
+//	  "fields": {"amount": ["GÖNDERİLEN TUTAR\\s*:\\s*([0-9.,]+)"]}}]
+//
+// Every matching keyword adds logoWeight to the detection score. Field
+// patterns are case-insensitive regexes whose first capture group is the
+// value; fields without patterns are read with the generic patterns.
+type bankTemplate struct {
+	Name     string              `json:"name"`
+	Keywords []string            `json:"keywords"`
+	Fields   map[string][]string `json:"fields"`
+}
+
+// templateFields maps the template field names to the patterns they extend
+var templateFields = map[string]func(*fieldPatterns) *[]*regexp.Regexp{
+	fieldRecipient:   func(fp *fieldPatterns) *[]*regexp.Regexp { return &fp.recipient },
+	fieldSender:      func(fp *fieldPatterns) *[]*regexp.Regexp { return &fp.sender },
+	fieldDescription: func(fp *fieldPatterns) *[]*regexp.Regexp { return &fp.description },
+	fieldAmount:      func(fp *fieldPatterns) *[]*regexp.Regexp { return &fp.amount },
+	fieldDate:        func(fp *fieldPatterns) *[]*regexp.Regexp { return &fp.date },
+	fieldReference:   func(fp *fieldPatterns) *[]*regexp.Regexp { return &fp.reference },
+}
+
+// reTurkishI matches the letters Go does not case-fold onto each other
+var reTurkishI = regexp.MustCompile(`[İIıi]`)
+
+// parseBankTemplates decodes and validates the CustomBankTemplates setting
+func parseBankTemplates(data string) ([]bankTemplate, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var templates []bankTemplate
+	if err := json.Unmarshal([]byte(data), &templates); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, parser := range defaultRegistry.parsers {
+		names[strings.ToLower(parser.Name())] = true
+	}
+	for i, template := range templates {
+		if strings.TrimSpace(template.Name) == "" {
+			return nil, fmt.Errorf("template %d: name is required", i+1)
+		}
+		if names[strings.ToLower(template.Name)] {
+			return nil, fmt.Errorf("template %q: a bank with this name already exists", template.Name)
+		}
+		names[strings.ToLower(template.Name)] = true
+
+		if err := template.validate(); err != nil {
+			return nil, fmt.Errorf("template %q: %w", template.Name, err)
+		}
+	}
+	return templates, nil
+}
+
+// validate checks that the template can be detected and that its patterns
+// compile and capture a value
+func (bt bankTemplate) validate() error {
+	if len(bt.Keywords) == 0 {
+		return errors.New("at least one keyword is required")
+	}
+	for _, keyword := range bt.Keywords {
+		if strings.TrimSpace(keyword) == "" {
+			return errors.New("keywords must not be empty")
+		}
+	}
+
+	for field, patterns := range bt.Fields {
+		if _, ok := templateFields[field]; !ok {
+			return fmt.Errorf("unknown field %q", field)
+		}
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(`(?i)` + pattern)
+			if err != nil {
+				return fmt.Errorf("field %q: %w", field, err)
+			}
+			if re.NumSubexp() == 0 {
+				return fmt.Errorf("field %q: pattern %q has no capture group", field, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// newTemplateParser returns a parser for a validated template. The
+// template's patterns are tried before the generic ones.
+func newTemplateParser(template bankTemplate) BankParser {
+	var signals []detectSignal
+	for _, keyword := range template.Keywords {
+		pattern := reTurkishI.ReplaceAllString(regexp.QuoteMeta(strings.TrimSpace(keyword)), `[İIıi]`)
+		signals = append(signals, signal(pattern, logoWeight))
+	}
+
+	patterns := genericPatterns()
+	for field, fieldRegexes := range template.Fields {
+		target := templateFields[field](&patterns)
+		var custom []*regexp.Regexp
+		for _, pattern := range fieldRegexes {
+			custom = append(custom, regexp.MustCompile(`(?i)`+pattern))
+		}
+		*target = append(custom, *target...)
+	}
+
+	return &patternParser{
+		name:     template.Name,
+		signals:  signals,
+		patterns: patterns,
+	}
+}
+
+// newTemplateRegistry returns the built-in parsers followed by the parsers
+// of the templates. Built-in banks win detection ties.
+func newTemplateRegistry(templates []bankTemplate) *parserRegistry {
+	registry := newDefaultRegistry()
+	for _, template := range templates {
+		registry.Register(newTemplateParser(template))
+	}
+	return registry
+}
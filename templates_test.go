@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+)
+
+const testBankTemplates = `[{
+	"name": "İnci Bank",
+	"keywords": ["İNCİ BANK", "incibank.com.tr"],
+	"fields": {
+		"recipient": ["LEHTAR\\s*:\\s*(.+)"],
+		"amount": ["GÖNDERİLEN\\s*:\\s*([0-9.,]+\\s*(?:TL|USD)?)"],
+		"reference_number": ["İŞLEM KODU\\s*:\\s*([A-Z0-9]+)"]
+	}
+}]`
+
+func TestParseBankTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		count   int
+		wantErr string
+	}{
+		{name: "empty setting", input: " "},
+		{name: "valid template", input: testBankTemplates, count: 1},
+		{name: "invalid JSON", input: `[{"name": }]`, wantErr: "invalid JSON"},
+		{name: "missing name", input: `[{"keywords": ["X BANK"]}]`, wantErr: "template 1: name is required"},
+		{name: "built-in bank name", input: `[{"name": "akbank", "keywords": ["AKBANK"]}]`, wantErr: "already exists"},
+		{name: "duplicate name", input: `[{"name": "X", "keywords": ["X"]}, {"name": "x", "keywords": ["Y"]}]`, wantErr: "already exists"},
+		{name: "no keywords", input: `[{"name": "X"}]`, wantErr: "at least one keyword"},
+		{name: "blank keyword", input: `[{"name": "X", "keywords": [" "]}]`, wantErr: "must not be empty"},
+		{name: "unknown field", input: `[{"name": "X", "keywords": ["X"], "fields": {"iban": ["(.+)"]}}]`, wantErr: `unknown field "iban"`},
+		{name: "invalid regex", input: `[{"name": "X", "keywords": ["X"], "fields": {"amount": ["([0-9"]}}]`, wantErr: `field "amount"`},
+		{name: "no capture group", input: `[{"name": "X", "keywords": ["X"], "fields": {"sender": ["GÖNDEREN.*"]}}]`, wantErr: "no capture group"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := parseBankTemplates(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseBankTemplates() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || len(templates) != tt.count {
+				t.Errorf("parseBankTemplates() = %d templates, %v, want %d", len(templates), err, tt.count)
+			}
+		})
+	}
+}
+
+func TestTemplateParser(t *testing.T) {
+	templates, err := parseBankTemplates(testBankTemplates)
+	if err != nil {
+		t.Fatalf("parseBankTemplates() error = %v", err)
+	}
+	registry := newTemplateRegistry(templates)
+
+	receipt := registry.extractReceipt("inci bank a.ş.\nLEHTAR : Deniz Ticaret\nGÖNDEREN : Ahmet Kaya\n" +
+		"GÖNDERİLEN : 1.250,00 TL\nİŞLEM KODU : IB778899\nİŞLEM TARİHİ : 01.08.2025")
+	if receipt == nil {
+		t.Fatal("extractReceipt() = nil, want receipt")
+	}
+	if receipt.Bank != "İnci Bank" || receipt.BankConfidence != logoWeight {
+		t.Errorf("Bank = %q (%v), want İnci Bank (%v)", receipt.Bank, receipt.BankConfidence, logoWeight)
+	}
+	if receipt.Recipient != "Deniz Ticaret" || receipt.Sources[fieldRecipient].Rule != "İnci Bank.recipient[0]" {
+		t.Errorf("Recipient = %q from %q, want the template pattern", receipt.Recipient, receipt.Sources[fieldRecipient].Rule)
+	}
+	// Fields without template patterns use the generic ones
+	if receipt.Sender != "Ahmet Kaya" || receipt.Date.IsZero() {
+		t.Errorf("Sender, Date = %q, %v, want generic matches", receipt.Sender, receipt.Date)
+	}
+	if receipt.Amount != 125000 || receipt.Currency != "TRY" || receipt.ReferenceNumber != "IB778899" {
+		t.Errorf("Amount, Currency, ReferenceNumber = %v, %q, %q", receipt.Amount, receipt.Currency, receipt.ReferenceNumber)
+	}
+
+	if !strings.Contains(formatSupportedBanks(registry), "- İnci Bank") {
+		t.Error("formatSupportedBanks() does not list the template bank")
+	}
+	if defaultRegistry.extractReceipt("İNCİ BANK\nALICI: Test\nTUTAR: 1,00 TL").Bank != "" {
+		t.Error("template bank leaked into the default registry")
+	}
+}
+
+func TestOnConfigurationChangeTemplates(t *testing.T) {
+	load := func(templates string) *plugintest.API {
+		api := &plugintest.API{}
+		api.On("LoadPluginConfiguration", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(*Configuration).CustomBankTemplates = templates
+		}).Return(nil)
+		api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		return api
+	}
+
+	p := &Plugin{}
+	p.SetAPI(load(testBankTemplates))
+	if err := p.OnConfigurationChange(); err != nil {
+		t.Fatalf("OnConfigurationChange() error = %v", err)
+	}
+	valid := p.getConfiguration()
+	if valid.parserRegistry() == defaultRegistry {
+		t.Error("parserRegistry() = default registry, want the template registry")
+	}
+
+	p.SetAPI(load(`[{"name": "X"}]`))
+	if err := p.OnConfigurationChange(); err == nil || !strings.Contains(err.Error(), "invalid custom bank templates") {
+		t.Errorf("OnConfigurationChange() error = %v, want invalid templates", err)
+	}
+	if p.getConfiguration() != valid {
+		t.Error("invalid templates replaced the active configuration")
+	}
+
+	// Activation goes on with the built-in parsers
+	p = &Plugin{}
+	p.SetAPI(load(`[{"name": "X"}]`))
+	if err := p.OnConfigurationChange(); err != nil {
+		t.Fatalf("OnConfigurationChange() during activation error = %v, want nil", err)
+	}
+	if p.getConfiguration().parserRegistry() != defaultRegistry {
+		t.Error("parserRegistry() after invalid templates during activation is not the default registry")
+	}
+}
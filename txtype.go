@@ -0,0 +1,85 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TransactionType is the kind of payment a receipt documents
+type TransactionType string
+
+// Transaction types, stored with every receipt
+const (
+	typeEFT        TransactionType = "eft"
+	typeHavale     TransactionType = "havale"
+	typeFAST       TransactionType = "fast"
+	typeSWIFT      TransactionType = "swift"
+	typeVirman     TransactionType = "virman"
+	typeBill       TransactionType = "fatura"
+	typeCreditCard TransactionType = "kredi_karti"
+	typeTaxPayment TransactionType = "vergi_sgk"
+)
+
+// unknownTypeLabel is shown for receipts whose type was not recognized
+const unknownTypeLabel = "Tespit edilemedi"
+
+// transactionTypeRule recognizes a transaction type by the wording of the
+// receipt
+type transactionTypeRule struct {
+	typ   TransactionType
+	label string
+	re    *regexp.Regexp
+}
+
+// transactionTypeRules are tried in order, so payment types that also
+// mention a transfer method, such as a tax payment sent by EFT, come first.
+var transactionTypeRules = []transactionTypeRule{
+	{typeSWIFT, "SWIFT", regexp.MustCompile(`(?i)SWIFT\s*(?:TRANSFER|[İIıi][ŞS]LEM|MESAJ|REFERANS)|` +
+		`YURT\s*DI[ŞS]I\s*(?:PARA\s*|G[İIıi]DEN\s*)?(?:TRANSFER|HAVALE|G[ÖO]NDER)|\bMT\s*103\b`)},
+	{typeTaxPayment, "Vergi/SGK Ödemesi", regexp.MustCompile(`(?i)VERG[İIıi]\s*(?:[ÖO]DEME|T[ÜU]R[ÜU])|\bSGK\b|` +
+		`SOSYAL\s*G[ÜU]VENL[İIıi]K|GEL[İIıi]R\s*[İIıi]DARES[İIıi]|\bMTV\b|PR[İIıi]M\s*[ÖO]DEME|TAHAKKUK\s*NO`)},
+	{typeCreditCard, "Kredi Kartı Ödemesi", regexp.MustCompile(`(?i)KRED[İIıi]\s*KART[Iı]\s*(?:BORCU?\s*)?[ÖO]DEME|` +
+		`KART\s*BORCU\s*[ÖO]DEME`)},
+	{typeBill, "Fatura Ödemesi", regexp.MustCompile(`(?i)FATURA\s*[ÖO]DEME|ABONE(?:L[İIıi]K)?\s*NO|TES[İIıi]SAT\s*NO`)},
+	{typeVirman, "Virman", regexp.MustCompile(`(?i)V[İIıi]RMAN|HESAPLARIM\s*ARASI`)},
+	{typeFAST, "FAST", regexp.MustCompile(`(?i)\bFAST\b|FONLARIN\s*ANLIK`)},
+	{typeEFT, "EFT", regexp.MustCompile(`(?i)\bEFT\b|ELEKTRON[İIıi]K\s*FON\s*TRANSFER`)},
+	{typeHavale, "Havale", regexp.MustCompile(`(?i)HAVALE`)},
+}
+
+// classifyTransaction returns the type of the transaction in text and
+// records its source on the receipt. The description is ignored, as
+// "Fatura ödemesi" there says what a transfer paid for rather than how.
+// Without any wording, a transfer between two accounts of the same bank is a
+// havale and one between banks an EFT.
+func classifyTransaction(text string, receipt *Receipt) TransactionType {
+	if receipt.Description != "" {
+		text = strings.ReplaceAll(text, receipt.Description, "")
+	}
+	for _, rule := range transactionTypeRules {
+		if rule.re.MatchString(text) {
+			receipt.setSource(fieldType, FieldSource{Rule: "type." + string(rule.typ), Confidence: confidenceHigh})
+			return rule.typ
+		}
+	}
+
+	if validIBAN(receipt.SenderIBAN) && validIBAN(receipt.RecipientIBAN) &&
+		strings.HasPrefix(receipt.SenderIBAN, "TR") && strings.HasPrefix(receipt.RecipientIBAN, "TR") {
+		receipt.setSource(fieldType, FieldSource{Rule: "type.iban_banks", Confidence: confidenceLow})
+		if receipt.SenderIBAN[4:9] == receipt.RecipientIBAN[4:9] {
+			return typeHavale
+		}
+		return typeEFT
+	}
+	return ""
+}
+
+// Label returns the Turkish display name of the transaction type
+func (t TransactionType) Label() string {
+	for _, rule := range transactionTypeRules {
+		if rule.typ == t {
+			return rule.label
+		}
+	}
+	return unknownTypeLabel
+}
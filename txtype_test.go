@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyTransaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected TransactionType
+	}{
+		{name: "EFT amount label", input: "GİDEN EFT TUTARI: 750,25 TL", expected: typeEFT},
+		{name: "havale amount label", input: "HAVALE TUTARI: 2,000.50 TL", expected: typeHavale},
+		{name: "combined havale and EFT title", input: "HAVALE/EFT DEKONTU\nTUTAR: 100,00 TL", expected: typeEFT},
+		{name: "FAST transfer", input: "FAST İŞLEM DEKONTU\nTUTAR: 100,00 TL", expected: typeFAST},
+		{name: "SWIFT transfer", input: "SWIFT TRANSFER DEKONTU\nTUTAR: 1.000,00 USD", expected: typeSWIFT},
+		{name: "abroad transfer", input: "YURTDIŞI PARA TRANSFERİ\nTUTAR: 500,00 EUR", expected: typeSWIFT},
+		{name: "outgoing abroad transfer", input: "YURT DIŞI GİDEN TRANSFER DEKONTU\nTUTAR: 5,000.00 USD", expected: typeSWIFT},
+		{name: "SWIFT code alone is not a SWIFT transfer", input: "SWIFT KODU: KTEFTRIS\nHAVALE TUTARI: 10,00 TL", expected: typeHavale},
+		{name: "virman", input: "VİRMAN DEKONTU\nTUTAR: 100,00 TL", expected: typeVirman},
+		{name: "bill payment", input: "FATURA ÖDEME DEKONTU\nABONE NO: 123456\nTUTAR: 250,00 TL", expected: typeBill},
+		{name: "credit card payment", input: "KREDİ KARTI BORCU ÖDEME\nTUTAR: 3.000,00 TL", expected: typeCreditCard},
+		{name: "tax payment sent by EFT", input: "VERGİ ÖDEME DEKONTU\nEFT\nTUTAR: 1.000,00 TL", expected: typeTaxPayment},
+		{name: "SGK premium", input: "SGK PRİM ÖDEMESİ\nTUTAR: 4.500,00 TL", expected: typeTaxPayment},
+		{name: "description does not classify", input: "AÇIKLAMA: Fatura ödemesi\nHAVALE TUTARI: 100,00 TL", expected: typeHavale},
+		{name: "same bank IBANs", input: "GÖNDEREN IBAN: " + testZiraatIBAN + "\nALICI IBAN: TR350001000000000987654321\nTUTAR: 1,00 TL", expected: typeHavale},
+		{name: "different bank IBANs", input: "GÖNDEREN IBAN: " + testZiraatIBAN + "\nALICI IBAN: " + testIsBankIBAN + "\nTUTAR: 1,00 TL", expected: typeEFT},
+		{name: "unknown", input: "ALICI: Test User\nTUTAR: 100,00 TL", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := extractReceipt(tt.input)
+			if receipt == nil {
+				t.Fatal("extractReceipt() = nil, want receipt")
+			}
+			if receipt.Type != tt.expected {
+				t.Errorf("Type = %q, want %q", receipt.Type, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTransactionTypeLabel(t *testing.T) {
+	if label := typeTaxPayment.Label(); label != "Vergi/SGK Ödemesi" {
+		t.Errorf("Label() = %q, want %q", label, "Vergi/SGK Ödemesi")
+	}
+	if label := TransactionType("").Label(); label != unknownTypeLabel {
+		t.Errorf("Label() = %q, want %q", label, unknownTypeLabel)
+	}
+
+	message := formatMessage([]*Receipt{{Bank: "HalkBank", BankConfidence: 0.8, Type: typeFAST, Recipient: "Test User"}},
+		&Configuration{HideCredits: true})
+	if !strings.Contains(message, "güven)\n**İşlem Türü**: FAST\n**Alıcı**: Test User") {
+		t.Errorf("formatMessage() = %q, want the transaction type after the bank", message)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+)
+
+// Outgoing webhooks POST every parsed receipt as JSON to the configured
+// URLs. The request is signed with HMAC-SHA256 over the timestamp header, a
+// dot and the body, keyed with WebhookSecret. Failed deliveries are retried
+// with exponential backoff; their state is kept in the KV store so retries
+// survive restarts and are sent by one server of a cluster.
+const (
+	webhookEventReceiptParsed = "receipt.parsed"
+
+	webhookEventHeader     = "X-Dekont-Event"
+	webhookDeliveryHeader  = "X-Dekont-Delivery"
+	webhookTimestampHeader = "X-Dekont-Timestamp"
+	webhookSignatureHeader = "X-Dekont-Signature"
+
+	// webhookRetryInterval is how often the job looks for deliveries to send
+	webhookRetryInterval = 30 * time.Second
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 10 * time.Second
+	// A delivery is attempted webhookMaxAttempts times, waiting
+	// webhookInitialBackoff after the first failure and twice as long after
+	// every next one, up to webhookMaxBackoff
+	webhookMaxAttempts    = 8
+	webhookInitialBackoff = time.Minute
+	webhookMaxBackoff     = time.Hour
+	// webhookBatchSize bounds the deliveries sent in one run of the job
+	webhookBatchSize = 50
+
+	// webhookLockKey is held by the server sending the deliveries
+	webhookLockKey = "webhook_lock"
+	webhookLockTTL = 10 * 60
+)
+
+// Webhook delivery states
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed"
+)
+
+// webhookClient sends the webhook requests
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// WebhookDelivery is a webhook request to one URL and the outcome of its
+// attempts. The payload is kept so that retries send the same body.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	URL            string          `json:"url"`
+	Event          string          `json:"event"`
+	TransactionID  string          `json:"transaction_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	CreateAt       int64           `json:"create_at"`
+	NextAttemptAt  int64           `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  int64           `json:"last_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+}
+
+// webhookPayload is the body of a webhook request
+type webhookPayload struct {
+	Event       string       `json:"event"`
+	DeliveryID  string       `json:"delivery_id"`
+	PostURL     string       `json:"post_url"`
+	Transaction *Transaction `json:"transaction"`
+}
+
+// parseWebhookURLs returns the URLs of the WebhookURLs setting, separated by
+// commas or whitespace. Only absolute http and https URLs are accepted.
+func parseWebhookURLs(value string) ([]string, error) {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	urls := make([]string, 0, len(fields))
+	for _, field := range fields {
+		u, err := url.Parse(field)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q", field)
+		}
+		urls = append(urls, field)
+	}
+	return urls, nil
+}
+
+// queueWebhooks queues a delivery of every transaction to every webhook URL
+// and returns the number of deliveries queued
+func (p *Plugin) queueWebhooks(transactions []*Transaction, config *Configuration) int {
+	if len(config.webhookURLs) == 0 || len(transactions) == 0 {
+		return 0
+	}
+	if config.WebhookSecret == "" {
+		p.API.LogError("Webhook URLs are configured without a webhook secret, receipts are not sent")
+		return 0
+	}
+
+	queued := 0
+	for _, tx := range transactions {
+		for _, target := range config.webhookURLs {
+			delivery := &WebhookDelivery{
+				ID:            model.NewId(),
+				URL:           target,
+				Event:         webhookEventReceiptParsed,
+				TransactionID: tx.ID,
+				Status:        deliveryPending,
+				CreateAt:      model.GetMillis(),
+			}
+			payload, err := json.Marshal(webhookPayload{
+				Event:       delivery.Event,
+				DeliveryID:  delivery.ID,
+				PostURL:     p.permalink(tx.PostID),
+				Transaction: tx,
+			})
+			if err == nil {
+				delivery.Payload = payload
+				err = p.store.QueueDelivery(delivery)
+			}
+			if err != nil {
+				p.API.LogError("Failed to queue webhook delivery",
+					"transactionId", tx.ID,
+					"url", target,
+					"error", err.Error())
+				continue
+			}
+			queued++
+		}
+	}
+	return queued
+}
+
+// processWebhookQueue sends the pending deliveries that are due at now
+func (p *Plugin) processWebhookQueue(now time.Time) {
+	locked, unlock := p.tryLock(webhookLockKey, webhookLockTTL)
+	if !locked {
+		return
+	}
+	defer unlock()
+
+	pending, err := p.store.PendingDeliveries()
+	if err != nil {
+		p.API.LogError("Failed to read pending webhook deliveries", "error", err.Error())
+		return
+	}
+
+	secret := p.getConfiguration().WebhookSecret
+	sent := 0
+	for _, delivery := range pending {
+		if sent == webhookBatchSize {
+			break
+		}
+		if delivery.NextAttemptAt > now.UnixMilli() {
+			continue
+		}
+		sent++
+
+		p.attemptDelivery(delivery, secret, now)
+		if err := p.store.SaveDelivery(delivery); err != nil {
+			p.API.LogError("Failed to save webhook delivery", "deliveryId", delivery.ID, "error", err.Error())
+		}
+	}
+}
+
+// attemptDelivery sends a delivery once and updates its state with the
+// outcome, scheduling the next attempt after a failure
+func (p *Plugin) attemptDelivery(delivery *WebhookDelivery, secret string, now time.Time) {
+	delivery.Attempts++
+	delivery.LastAttemptAt = now.UnixMilli()
+	delivery.NextAttemptAt = 0
+
+	statusCode, err := sendWebhook(delivery, secret, now)
+	delivery.LastStatusCode = statusCode
+	switch {
+	case err == nil:
+		delivery.Status, delivery.LastError = deliveryDelivered, ""
+
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status, delivery.LastError = deliveryFailed, err.Error()
+		p.API.LogError("Webhook delivery failed",
+			"deliveryId", delivery.ID,
+			"url", delivery.URL,
+			"attempts", delivery.Attempts,
+			"error", err.Error())
+
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts)).UnixMilli()
+		p.API.LogWarn("Webhook delivery attempt failed, will retry",
+			"deliveryId", delivery.ID,
+			"url", delivery.URL,
+			"attempts", delivery.Attempts,
+			"error", err.Error())
+	}
+}
+
+// webhookBackoff returns the wait before the next attempt after the given
+// number of failed attempts
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookInitialBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+// sendWebhook POSTs the signed payload of a delivery and returns the status
+// code of the response. Responses other than 2xx are errors.
+func sendWebhook(delivery *WebhookDelivery, secret string, now time.Time) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, delivery.Event)
+	req.Header.Set(webhookDeliveryHeader, delivery.ID)
+	req.Header.Set(webhookTimestampHeader, timestamp)
+	req.Header.Set(webhookSignatureHeader, "sha256="+signWebhook(secret, timestamp, delivery.Payload))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the hex encoded HMAC-SHA256 of the timestamp, a dot
+// and the body
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
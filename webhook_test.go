@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v6/model"
+	"github.com/mattermost/mattermost-server/v6/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseWebhookURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "empty", value: "", expected: []string{}},
+		{name: "one per line", value: "https://erp.example.com/hook\nhttp://10.0.0.5:8080/dekont\n",
+			expected: []string{"https://erp.example.com/hook", "http://10.0.0.5:8080/dekont"}},
+		{name: "comma separated", value: "https://a.example.com, https://b.example.com",
+			expected: []string{"https://a.example.com", "https://b.example.com"}},
+		{name: "relative URL", value: "/hooks/dekont", wantErr: true},
+		{name: "unsupported scheme", value: "ftp://erp.example.com/hook", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := parseWebhookURLs(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWebhookURLs(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(urls) != len(tt.expected) {
+				t.Fatalf("parseWebhookURLs(%q) = %q, want %q", tt.value, urls, tt.expected)
+			}
+			for i := range urls {
+				if urls[i] != tt.expected[i] {
+					t.Errorf("parseWebhookURLs(%q)[%d] = %q, want %q", tt.value, i, urls[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOnConfigurationChangeWebhookURLs(t *testing.T) {
+	load := func(urls string) *plugintest.API {
+		api := &plugintest.API{}
+		api.On("LoadPluginConfiguration", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(*Configuration).WebhookURLs = urls
+		}).Return(nil)
+		api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		return api
+	}
+
+	// Activation goes on without webhooks
+	p := &Plugin{}
+	p.SetAPI(load("ftp://erp.example.com/hook"))
+	if err := p.OnConfigurationChange(); err != nil {
+		t.Fatalf("OnConfigurationChange() during activation error = %v, want nil", err)
+	}
+	if urls := p.getConfiguration().webhookURLs; len(urls) != 0 {
+		t.Errorf("webhookURLs after invalid URLs during activation = %q, want none", urls)
+	}
+
+	p.SetAPI(load("https://erp.example.com/hook"))
+	if err := p.OnConfigurationChange(); err != nil {
+		t.Fatalf("OnConfigurationChange() error = %v", err)
+	}
+	valid := p.getConfiguration()
+
+	p.SetAPI(load("/hooks/dekont"))
+	if err := p.OnConfigurationChange(); err == nil {
+		t.Error("OnConfigurationChange() with invalid webhook URLs error = nil, want an error")
+	}
+	if p.getConfiguration() != valid {
+		t.Error("invalid webhook URLs replaced the active configuration")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{7, time.Hour},
+		{20, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if result := webhookBackoff(tt.attempts); result != tt.expected {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, result, tt.expected)
+		}
+	}
+}
+
+// webhookReceiver is an httptest server answering webhook requests with the
+// queued status codes, then 200
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	receiver := &webhookReceiver{statuses: statuses}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		status := http.StatusOK
+		if len(receiver.statuses) > 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+// newWebhookTestPlugin returns a plugin with an in-memory store sending
+// webhooks to the given URLs
+func newWebhookTestPlugin(t *testing.T, urls ...string) (*Plugin, *memoryKV) {
+	api, kv := newKVTestAPI()
+	api.On("GetConfig").Return(&model.Config{}).Maybe()
+	p := &Plugin{botUserID: "bot", configuration: &Configuration{WebhookSecret: "s3cret", webhookURLs: urls}}
+	p.SetAPI(api)
+	p.store = newTransactionStore(api)
+	return p, kv
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	p, kv := newWebhookTestPlugin(t, receiver.URL)
+
+	tx := &Transaction{ID: "file1_0", FileID: "file1", PostID: "post1", ChannelID: "finance", Receipt: &Receipt{
+		Bank: "HalkBank", Recipient: "ABC Şirketi", Amount: 150000, Currency: "TRY", ReferenceNumber: "HB123"}}
+	if queued := p.queueWebhooks([]*Transaction{tx}, p.getConfiguration()); queued != 1 {
+		t.Fatalf("queueWebhooks() = %d, want 1", queued)
+	}
+
+	now := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+	p.processWebhookQueue(now)
+	if len(receiver.requests) != 1 {
+		t.Fatalf("receiver got %d requests after the first run, want 1", len(receiver.requests))
+	}
+
+	// Not due before the backoff has passed
+	p.processWebhookQueue(now.Add(30 * time.Second))
+	if len(receiver.requests) != 1 {
+		t.Fatalf("receiver got %d requests before the retry was due, want 1", len(receiver.requests))
+	}
+	p.processWebhookQueue(now.Add(time.Minute))
+	p.processWebhookQueue(now.Add(3 * time.Minute))
+	if len(receiver.requests) != 3 {
+		t.Fatalf("receiver got %d requests, want 3", len(receiver.requests))
+	}
+
+	request, body := receiver.requests[2], receiver.bodies[2]
+	if body := string(body); body != string(receiver.bodies[0]) {
+		t.Errorf("retry body = %q, want the body of the first attempt %q", body, receiver.bodies[0])
+	}
+	timestamp := request.Header.Get(webhookTimestampHeader)
+	if timestamp != strconv.FormatInt(now.Add(3*time.Minute).Unix(), 10) {
+		t.Errorf("%s = %q, want the time of the attempt", webhookTimestampHeader, timestamp)
+	}
+	if signature, want := request.Header.Get(webhookSignatureHeader), "sha256="+signWebhook("s3cret", timestamp, body); signature != want {
+		t.Errorf("%s = %q, want %q", webhookSignatureHeader, signature, want)
+	}
+	if event := request.Header.Get(webhookEventHeader); event != webhookEventReceiptParsed {
+		t.Errorf("%s = %q, want %q", webhookEventHeader, event, webhookEventReceiptParsed)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.DeliveryID != request.Header.Get(webhookDeliveryHeader) || payload.PostURL != "/_redirect/pl/post1" ||
+		payload.Transaction == nil || payload.Transaction.Receipt.ReferenceNumber != "HB123" || payload.Transaction.Receipt.Amount != 150000 {
+		t.Errorf("payload = %s, want the transaction with its delivery ID and post URL", body)
+	}
+
+	deliveries, err := p.store.ListDeliveries()
+	if err != nil {
+		t.Fatalf("ListDeliveries() error = %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("ListDeliveries() returned %d deliveries, want 1", len(deliveries))
+	}
+	delivery := deliveries[0]
+	if delivery.Status != deliveryDelivered || delivery.Attempts != 3 || delivery.LastStatusCode != http.StatusOK || delivery.LastError != "" {
+		t.Errorf("delivery = %+v, want delivered after 3 attempts", delivery)
+	}
+	if pending, _ := p.store.PendingDeliveries(); len(pending) != 0 {
+		t.Errorf("PendingDeliveries() = %d deliveries, want 0", len(pending))
+	}
+	if kv.get(webhookLockKey) != nil {
+		t.Error("processWebhookQueue() did not release the lock")
+	}
+}
+
+func TestWebhookDeliveryGivesUp(t *testing.T) {
+	statuses := make([]int, webhookMaxAttempts)
+	for i := range statuses {
+		statuses[i] = http.StatusServiceUnavailable
+	}
+	receiver := newWebhookReceiver(t, statuses...)
+	p, _ := newWebhookTestPlugin(t, receiver.URL)
+
+	p.queueWebhooks([]*Transaction{{ID: "file1_0", Receipt: &Receipt{Amount: 100}}}, p.getConfiguration())
+
+	now := time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < webhookMaxAttempts+2; i++ {
+		p.processWebhookQueue(now)
+		now = now.Add(webhookMaxBackoff)
+	}
+
+	if len(receiver.requests) != webhookMaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", len(receiver.requests), webhookMaxAttempts)
+	}
+	deliveries, _ := p.store.ListDeliveries()
+	if len(deliveries) != 1 || deliveries[0].Status != deliveryFailed || deliveries[0].LastStatusCode != http.StatusServiceUnavailable {
+		t.Errorf("ListDeliveries() = %+v, want one failed delivery", deliveries)
+	}
+}
+
+func TestQueueWebhooksWithoutSecret(t *testing.T) {
+	receiver := newWebhookReceiver(t)
+	p, _ := newWebhookTestPlugin(t, receiver.URL)
+	p.configuration.WebhookSecret = ""
+
+	if queued := p.queueWebhooks([]*Transaction{{ID: "file1_0"}}, p.getConfiguration()); queued != 0 {
+		t.Errorf("queueWebhooks() without a secret = %d, want 0", queued)
+	}
+}